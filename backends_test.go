@@ -0,0 +1,135 @@
+package intmap_test
+
+import (
+	"math/rand"
+	"testing"
+	"unsafe"
+
+	"github.com/db47h/intmap"
+)
+
+// intMap is the core subset of the Map API implemented by the alternative map
+// types.
+type intMap interface {
+	Set(key int, value int)
+	Get(key int) (int, bool)
+	Has(key int) bool
+	Delete(key int) bool
+	Len() int
+	Range(f func(key, value int) bool)
+}
+
+func TestBackends(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		m    intMap
+	}{
+		{"RobinHood", intmap.NewRobinHood[int](16, 0.95)},
+		{"Hopscotch", intmap.NewHopscotch[int](16, 0.9)},
+		{"Cuckoo", intmap.NewCuckoo[int](16)},
+		{"Swiss", new(intmap.SwissMap[int])},
+		{"Occupancy", new(intmap.OccupancyMap[int])},
+		{"Split", new(intmap.SplitMap[int])},
+		{"Slab", new(intmap.SlabMap[int])},
+		{"MapOf", &keyedMap[uint64]{
+			m:    new(intmap.MapOf[uint64, int]),
+			key:  func(k int) uint64 { return uint64(k) << 22 * 7919 },
+			from: func(k uint64) int { return int(int64(k*0xb1e5af8146e4d00f) >> 22) },
+		}},
+		{"Map32", &keyedMap[int32]{
+			m:    &new(intmap.Map32[int]).MapOf,
+			key:  func(k int) int32 { return int32(k) * 7919 },
+			from: func(k int32) int { return int(k / 7919) },
+		}},
+		// keys only differ in their high 32 bits, which would be truncated
+		// by a Map on 32 bits platforms.
+		{"Map64", &keyedMap[int64]{
+			m:    &new(intmap.Map64[int]).MapOf,
+			key:  func(k int) int64 { return int64(k) << 32 },
+			from: func(k int64) int { return int(k >> 32) },
+		}},
+		{"Ptr", new(ptrMap)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			testRandomOps(t, tc.m)
+		})
+	}
+}
+
+// keyedMap adapts a MapOf to intMap by converting keys.
+type keyedMap[K intmap.Integer] struct {
+	m    *intmap.MapOf[K, int]
+	key  func(int) K
+	from func(K) int
+}
+
+func (m *keyedMap[K]) Set(key, value int)      { m.m.Set(m.key(key), value) }
+func (m *keyedMap[K]) Get(key int) (int, bool) { return m.m.Get(m.key(key)) }
+func (m *keyedMap[K]) Has(key int) bool        { return m.m.Has(m.key(key)) }
+func (m *keyedMap[K]) Delete(key int) bool     { return m.m.Delete(m.key(key)) }
+func (m *keyedMap[K]) Len() int                { return m.m.Len() }
+func (m *keyedMap[K]) Range(f func(k, v int) bool) {
+	m.m.Range(func(k K, v int) bool { return f(m.from(k), v) })
+}
+
+// ptrMap adapts a PtrMap to intMap by using the addresses of the bytes of buf
+// as keys, which are not aligned.
+type ptrMap struct {
+	m   intmap.PtrMap[int]
+	buf [4096]byte
+}
+
+func (m *ptrMap) key(k int) unsafe.Pointer { return unsafe.Pointer(&m.buf[k+2048]) }
+func (m *ptrMap) Set(key, value int)       { m.m.Set(m.key(key), value) }
+func (m *ptrMap) Get(key int) (int, bool)  { return m.m.Get(m.key(key)) }
+func (m *ptrMap) Has(key int) bool         { return m.m.Has(m.key(key)) }
+func (m *ptrMap) Delete(key int) bool      { return m.m.Delete(m.key(key)) }
+func (m *ptrMap) Len() int                 { return m.m.Len() }
+func (m *ptrMap) Range(f func(k, v int) bool) {
+	m.m.Range(func(k unsafe.Pointer, v int) bool {
+		return f(int(uintptr(k)-uintptr(unsafe.Pointer(&m.buf[0])))-2048, v)
+	})
+}
+
+// testRandomOps applies a random sequence of insertions and deletions to m and
+// checks it against a builtin map.
+func testRandomOps(t *testing.T, m intMap) {
+	r := rand.New(rand.NewSource(424242))
+	sm := make(map[int]int)
+	for i := 0; i < 200000; i++ {
+		k := r.Intn(4096) - 2048
+		if r.Intn(3) == 0 {
+			_, exp := sm[k]
+			if ok := m.Delete(k); ok != exp {
+				t.Fatalf("Delete(%d) = %v, expected %v", k, ok, exp)
+			}
+			delete(sm, k)
+			continue
+		}
+		m.Set(k, i)
+		sm[k] = i
+	}
+	if m.Len() != len(sm) {
+		t.Fatalf("Len() = %d, expected %d", m.Len(), len(sm))
+	}
+	for k := -2048; k < 2048; k++ {
+		v, ok := m.Get(k)
+		if ev, eok := sm[k]; ok != eok || v != ev {
+			t.Fatalf("bad value for key %d: %d, %v; expected %d, %v", k, v, ok, ev, eok)
+		}
+		if m.Has(k) != ok {
+			t.Fatalf("Has(%d) = %v, expected %v", k, !ok, ok)
+		}
+	}
+	cnt := 0
+	m.Range(func(k, v int) bool {
+		if sm[k] != v {
+			t.Errorf("bad value for key %d: %d", k, v)
+		}
+		cnt++
+		return true
+	})
+	if cnt != len(sm) {
+		t.Errorf("Range visited %d keys, expected %d", cnt, len(sm))
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+// MinKey returns the smallest key in the map and ok set to true. If the map is
+// empty, it returns 0 and false.
+//
+// The key bounds are maintained incrementally as keys are inserted. Deleting
+// the current smallest or largest key invalidates them, and the next call to
+// MinKey or MaxKey will scan the whole map.
+//
+func (m *Map[V]) MinKey() (key int, ok bool) {
+	if m.Len() == 0 {
+		return 0, false
+	}
+	m.updateBounds()
+	return m.minKey, true
+}
+
+// MaxKey returns the largest key in the map and ok set to true. If the map is
+// empty, it returns 0 and false.
+//
+// See MinKey for performance considerations.
+//
+func (m *Map[V]) MaxKey() (key int, ok bool) {
+	if m.Len() == 0 {
+		return 0, false
+	}
+	m.updateBounds()
+	return m.maxKey, true
+}
+
+func (m *Map[V]) updateBounds() {
+	if m.boundsValid {
+		return
+	}
+	m.minKey, m.maxKey = maxInt, minInt
+	m.boundsValid = true
+	m.Range(func(k int, _ V) bool {
+		m.track(k)
+		return true
+	})
+}
+
+// track updates the key bounds for a newly inserted key.
+//
+func (m *Map[V]) track(key int) {
+	if !m.boundsValid {
+		return
+	}
+	if key < m.minKey {
+		m.minKey = key
+	}
+	if key > m.maxKey {
+		m.maxKey = key
+	}
+}
+
+// untrack invalidates the key bounds if key, which is being deleted, is one of
+// them.
+//
+func (m *Map[V]) untrack(key int) {
+	if key == m.minKey || key == m.maxKey {
+		m.boundsValid = false
+	}
+}
+
+const (
+	maxInt = int(^uint(0) >> 1)
+	minInt = -maxInt - 1
+)
@@ -0,0 +1,40 @@
+package intmap_test
+
+import (
+	"testing"
+
+	"github.com/db47h/intmap"
+)
+
+func TestMap_MinKey(t *testing.T) {
+	var m intmap.Map[int]
+	check := func(min, max int) {
+		t.Helper()
+		if k, ok := m.MinKey(); !ok || k != min {
+			t.Errorf("MinKey() = %d, %v; expected %d, true", k, ok, min)
+		}
+		if k, ok := m.MaxKey(); !ok || k != max {
+			t.Errorf("MaxKey() = %d, %v; expected %d, true", k, ok, max)
+		}
+	}
+
+	if _, ok := m.MinKey(); ok {
+		t.Error("MinKey succeeded on empty map")
+	}
+	for k := 1; k <= 100; k++ {
+		m.Set(k, k)
+	}
+	check(1, 100)
+	m.Set(-5, 0)
+	m.Set(0, 0)
+	check(-5, 100)
+	m.Delete(100)
+	m.Delete(-5)
+	check(0, 99)
+	m.Delete(0)
+	check(1, 99)
+	m.Clear()
+	if _, ok := m.MaxKey(); ok {
+		t.Error("MaxKey succeeded on empty map")
+	}
+}
@@ -0,0 +1,350 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"unsafe"
+)
+
+// Merge sets all key-value pairs from other in m. For keys present in both
+// maps, the new value is the one returned by resolve(key, a, b) where a is the
+// value in m and b the value in other. If resolve is nil, values from other
+// take precedence.
+//
+func (m *Map[V]) Merge(other *Map[V], resolve func(key int, a, b V) V) {
+	if other.hasFreeKey {
+		m.merge(other.free, other.freeKeyValue, resolve)
+	}
+	es := other.es
+	for i := range es {
+		if k := es[i].Key; k != other.free {
+			m.merge(k, es[i].Value, resolve)
+		}
+	}
+}
+
+func (m *Map[V]) merge(k int, v V, resolve func(key int, a, b V) V) {
+	if k == m.free {
+		if m.hasFreeKey && resolve != nil {
+			v = resolve(k, m.freeKeyValue, v)
+		}
+		m.setFreeKey(v)
+		return
+	}
+	idx, existed := m.reserve(k)
+	if existed && resolve != nil {
+		v = resolve(k, m.es[idx].Value, v)
+	}
+	m.es[idx].Value = v
+}
+
+// Copy sets all key-value pairs from src in dst. dst is grown beforehand in
+// order to avoid intermediate rehashes.
+//
+func Copy[V any](dst, src *Map[V]) {
+	dst.grow(src.size)
+	dst.Merge(src, nil)
+}
+
+// DeleteFunc deletes all key-value pairs for which del returns true. del is
+// called exactly once for each key in the map and must not modify the map.
+//
+func (m *Map[V]) DeleteFunc(del func(key int, value V) bool) {
+	if m.hasFreeKey && del(m.free, m.freeKeyValue) {
+		m.Delete(m.free)
+	}
+	es := m.es
+	l := len(es)
+	if m.size == 0 {
+		return
+	}
+	// Start right after a free slot: since keys are only ever shifted back
+	// towards a deleted slot within the same cluster, no key will be moved
+	// over it and past the current position.
+	mod := l - 1
+	start := 0
+	for es[start].Key != m.free {
+		start++
+	}
+	for n, i := 0, start; n < l; n++ {
+		i = nextIdx(i) & mod
+		for {
+			k := es[i].Key
+			if k == m.free || !del(k, es[i].Value) {
+				break
+			}
+			m.remove(i)
+		}
+	}
+	m.autoShrink()
+	m.autoCompact()
+}
+
+// Transform returns a new map with the same keys and capacity as m where each
+// value is set to f(key, value). Since the key layout is unchanged, keys are
+// not rehashed.
+//
+func Transform[V, U any](m *Map[V], f func(key int, value V) U) *Map[U] {
+	t := &Map[U]{
+		size:          m.size,
+		threshold:     m.threshold,
+		free:          m.free,
+		hasFreeKey:    m.hasFreeKey,
+		deterministic: m.deterministic,
+		fibonacci:     m.fibonacci,
+		triangular:    m.triangular,
+		tombstones:    m.tombstones,
+		tombs:         m.tombs,
+		tombBits:      slices.Clone(m.tombBits),
+		seed:          m.seed,
+		seedMode:      m.seedMode,
+	}
+	if m.hasFreeKey {
+		t.freeKeyValue = f(m.free, m.freeKeyValue)
+	}
+	if m.es != nil {
+		t.es = t.makeEntries(len(m.es))
+		for i := range m.es {
+			if k := m.es[i].Key; k != m.free {
+				t.es[i] = KeyValue[U]{k, f(k, m.es[i].Value)}
+			}
+		}
+	}
+	return t
+}
+
+// FromMap returns a new Map holding all key-value pairs from src, with the
+// default fill ratio and a capacity large enough to hold them.
+//
+func FromMap[V any](src map[int]V) *Map[V] {
+	var m Map[V]
+	m.SetAll(src)
+	return &m
+}
+
+// SetAll sets all key-value pairs from src in m. m is grown beforehand in order
+// to avoid intermediate rehashes.
+//
+func (m *Map[V]) SetAll(src map[int]V) {
+	m.grow(len(src))
+	for k, v := range src {
+		m.Set(k, v)
+	}
+}
+
+// ToGoMap returns a builtin map holding all key-value pairs from m.
+//
+func (m *Map[V]) ToGoMap() map[int]V {
+	gm := make(map[int]V, m.Len())
+	m.Range(func(k int, v V) bool {
+		gm[k] = v
+		return true
+	})
+	return gm
+}
+
+// NewFromKeys returns a new Map with the default fill ratio where each key from
+// keys is set to f(key). The map is sized beforehand to hold all keys.
+//
+func NewFromKeys[V any](keys []int, f func(key int) V) *Map[V] {
+	var m Map[V]
+	m.grow(len(keys))
+	for _, k := range keys {
+		m.Set(k, f(k))
+	}
+	return &m
+}
+
+// Zip returns a new Map with the default fill ratio where each key keys[i] is
+// set to values[i]. It returns an error if keys and values do not have the same
+// length.
+//
+func Zip[V any](keys []int, values []V) (*Map[V], error) {
+	if len(keys) != len(values) {
+		return nil, errors.New("keys and values have different lengths")
+	}
+	var m Map[V]
+	m.grow(len(keys))
+	for i, k := range keys {
+		m.Set(k, values[i])
+	}
+	return &m, nil
+}
+
+// GroupBy returns a new Map where each item from items is appended to the
+// slice at key(item). Items keep their relative order within each group.
+//
+func GroupBy[T any](items []T, key func(item T) int) *Map[[]T] {
+	var m Map[[]T]
+	for _, it := range items {
+		k := key(it)
+		if k == m.free {
+			m.setFreeKey(append(m.freeKeyValue, it))
+			continue
+		}
+		idx, _ := m.reserve(k)
+		m.es[idx].Value = append(m.es[idx].Value, it)
+	}
+	return &m
+}
+
+// Invert returns a new Map where each value of m is mapped to its key. It
+// returns an error if any value appears more than once in m.
+//
+func Invert(m *Map[int]) (*Map[int], error) {
+	var inv Map[int]
+	inv.grow(m.Len())
+	var err error
+	m.Range(func(k, v int) bool {
+		if _, dup := inv.Swap(v, k); dup {
+			err = errors.New("duplicate value " + strconv.Itoa(v))
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// ContainsValueFunc returns true if any value in the map is equal to v
+// according to eq.
+//
+func (m *Map[V]) ContainsValueFunc(v V, eq func(a, b V) bool) bool {
+	if m.hasFreeKey && eq(m.freeKeyValue, v) {
+		return true
+	}
+	es := m.es
+	for i := range es {
+		if es[i].Key != m.free && eq(es[i].Value, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsValue returns true if any value in the map is equal to v.
+//
+func ContainsValue[V comparable](m *Map[V], v V) bool {
+	return m.ContainsValueFunc(v, equal[V])
+}
+
+// KeysOfFunc returns an unordered slice of the keys whose value is equal to v
+// according to eq.
+//
+func (m *Map[V]) KeysOfFunc(v V, eq func(a, b V) bool) []int {
+	var ks []int
+	if m.hasFreeKey && eq(m.freeKeyValue, v) {
+		ks = append(ks, m.free)
+	}
+	es := m.es
+	for i := range es {
+		if k := es[i].Key; k != m.free && eq(es[i].Value, v) {
+			ks = append(ks, k)
+		}
+	}
+	return ks
+}
+
+// KeysOf returns an unordered slice of the keys whose value is equal to v.
+//
+func KeysOf[V comparable](m *Map[V], v V) []int {
+	return m.KeysOfFunc(v, equal[V])
+}
+
+// SetMany sets all key-value pairs from entries in m, in order. m is grown
+// beforehand in order to avoid intermediate rehashes.
+//
+// Like GetMany, SetMany prefetches the home slots of upcoming keys.
+//
+func (m *Map[V]) SetMany(entries []KeyValue[V]) {
+	m.grow(len(entries))
+	for i := 0; i < prefetchDist && i < len(entries); i++ {
+		m.prefetch(entries[i].Key)
+	}
+	for i := range entries {
+		if j := i + prefetchDist; j < len(entries) {
+			m.prefetch(entries[j].Key)
+		}
+		m.Set(entries[i].Key, entries[i].Value)
+	}
+}
+
+// prefetchDist is the number of keys whose home slot is prefetched ahead of
+// their use in GetMany and SetMany.
+//
+const prefetchDist = 16
+
+// prefetch hints the CPU to load the home slot of key into its caches.
+//
+func (m *Map[V]) prefetch(key int) {
+	if l := len(m.es); l > 0 {
+		prefetch(unsafe.Pointer(&m.es[m.slot(key, l-1)]))
+	}
+}
+
+// GetMany looks up all keys and sets out[i] and found[i] to the value and ok
+// results of Get(keys[i]). out must be at least as long as keys. found may be
+// nil, otherwise it must also be at least as long as keys.
+//
+// The home slots of keys are prefetched a few keys ahead of the one being
+// looked up, so that cache misses overlap instead of being serialized. On maps
+// that do not fit in the CPU caches, this is significantly faster than calling
+// Get for each key.
+//
+func (m *Map[V]) GetMany(keys []int, out []V, found []bool) {
+	out = out[:len(keys)]
+	if found != nil {
+		found = found[:len(keys)]
+	}
+	for i := 0; i < prefetchDist && i < len(keys); i++ {
+		m.prefetch(keys[i])
+	}
+	for i, k := range keys {
+		if j := i + prefetchDist; j < len(keys) {
+			m.prefetch(keys[j])
+		}
+		v, ok := m.Get(k)
+		out[i] = v
+		if found != nil {
+			found[i] = ok
+		}
+	}
+}
+
+// DeleteMany deletes all given keys from the map and returns the number of keys
+// that were actually present.
+//
+func (m *Map[V]) DeleteMany(keys []int) int {
+	n := 0
+	for _, k := range keys {
+		if m.Delete(k) {
+			n++
+		}
+	}
+	m.autoShrink()
+	m.autoCompact()
+	return n
+}
+
+// CountFunc returns the number of key-value pairs for which pred returns true.
+//
+func (m *Map[V]) CountFunc(pred func(key int, value V) bool) int {
+	n := 0
+	if m.hasFreeKey && pred(m.free, m.freeKeyValue) {
+		n++
+	}
+	es := m.es
+	for i := range es {
+		if k := es[i].Key; k != m.free && pred(k, es[i].Value) {
+			n++
+		}
+	}
+	return n
+}
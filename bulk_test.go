@@ -0,0 +1,301 @@
+package intmap_test
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/db47h/intmap"
+)
+
+func TestMap_Merge(t *testing.T) {
+	var a, b intmap.Map[int]
+	for k := 0; k < 100; k++ {
+		a.Set(k, 1)
+		b.Set(k+50, 2)
+	}
+	a.Merge(&b, func(_ int, x, y int) int { return x + y })
+	if a.Len() != 150 {
+		t.Fatalf("bad size: expected 150, got %d", a.Len())
+	}
+	for k := 0; k < 150; k++ {
+		exp := 1
+		switch {
+		case k >= 100:
+			exp = 2
+		case k >= 50:
+			exp = 3
+		}
+		if v, _ := a.Get(k); v != exp {
+			t.Errorf("bad value for key %d: expected %d, got %d", k, exp, v)
+		}
+	}
+}
+
+func TestCopy(t *testing.T) {
+	var dst, src intmap.Map[int]
+	for k := 0; k < 10000; k++ {
+		src.Set(k, k)
+	}
+	dst.Set(-1, -1)
+	intmap.Copy(&dst, &src)
+	if dst.Len() != 10001 {
+		t.Fatalf("bad size: expected 10001, got %d", dst.Len())
+	}
+	for k := -1; k < 10000; k++ {
+		if v, ok := dst.Get(k); !ok || v != k {
+			t.Errorf("Get(%d) = %v, %v; expected %v, true", k, v, ok, k)
+		}
+	}
+}
+
+func TestMap_DeleteFunc(t *testing.T) {
+	m := intmap.New[int](64, 0.99)
+	calls := make(map[int]int)
+	for k := 0; k < 60; k++ {
+		m.Set(k*64, k)
+	}
+	m.Set(0, 0)
+	m.DeleteFunc(func(k, v int) bool {
+		calls[k]++
+		return v%3 != 1
+	})
+	if m.Len() != 20 {
+		t.Errorf("bad size: expected 20, got %d", m.Len())
+	}
+	for k := 0; k < 60; k++ {
+		if calls[k*64] != 1 {
+			t.Errorf("del called %d times for key %d", calls[k*64], k*64)
+		}
+		v, ok := m.Get(k * 64)
+		if ok != (k%3 == 1) || ok && v != k {
+			t.Errorf("Get(%d) = %v, %v", k*64, v, ok)
+		}
+	}
+}
+
+func TestTransform(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 100; k++ {
+		m.Set(k, k)
+	}
+	s := intmap.Transform(&m, func(k, v int) string { return strconv.Itoa(k + v) })
+	if s.Len() != 100 {
+		t.Fatalf("bad size: expected 100, got %d", s.Len())
+	}
+	for k := 0; k < 100; k++ {
+		if v, _ := s.Get(k); v != strconv.Itoa(2*k) {
+			t.Errorf("bad value for key %d: expected %d, got %q", k, 2*k, v)
+		}
+	}
+}
+
+func TestFromMap(t *testing.T) {
+	src := make(map[int]int)
+	for k := 0; k < 1000; k++ {
+		src[k] = -k
+	}
+	m := intmap.FromMap(src)
+	if m.Len() != len(src) {
+		t.Fatalf("bad size: expected %d, got %d", len(src), m.Len())
+	}
+	for k, v := range src {
+		if vv, ok := m.Get(k); !ok || vv != v {
+			t.Errorf("Get(%d) = %v, %v; expected %v, true", k, vv, ok, v)
+		}
+	}
+}
+
+func TestMap_ToGoMap(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 1000; k++ {
+		m.Set(k, -k)
+	}
+	gm := m.ToGoMap()
+	if len(gm) != m.Len() {
+		t.Fatalf("bad size: expected %d, got %d", m.Len(), len(gm))
+	}
+	for k, v := range gm {
+		if v != -k {
+			t.Errorf("bad value for key %d: expected %d, got %d", k, -k, v)
+		}
+	}
+}
+
+func TestNewFromKeys(t *testing.T) {
+	keys := []int{0, 3, 5, 7, 11, 3}
+	m := intmap.NewFromKeys(keys, func(k int) int { return k * k })
+	if m.Len() != 5 {
+		t.Fatalf("bad size: expected 5, got %d", m.Len())
+	}
+	for _, k := range keys {
+		if v, _ := m.Get(k); v != k*k {
+			t.Errorf("bad value for key %d: expected %d, got %d", k, k*k, v)
+		}
+	}
+}
+
+func TestZip(t *testing.T) {
+	if _, err := intmap.Zip([]int{1, 2}, []string{"a"}); err == nil {
+		t.Error("Zip succeeded with mismatched lengths")
+	}
+	m, err := intmap.Zip([]int{1, 2, 0}, []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, exp := range map[int]string{1: "a", 2: "b", 0: "c"} {
+		if v, _ := m.Get(k); v != exp {
+			t.Errorf("bad value for key %d: expected %q, got %q", k, exp, v)
+		}
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	words := []string{"a", "bc", "de", "f", "ghi", "", "jk"}
+	m := intmap.GroupBy(words, func(s string) int { return len(s) })
+	exp := map[int][]string{0: {""}, 1: {"a", "f"}, 2: {"bc", "de", "jk"}, 3: {"ghi"}}
+	if m.Len() != len(exp) {
+		t.Fatalf("bad size: expected %d, got %d", len(exp), m.Len())
+	}
+	for k, e := range exp {
+		if v, _ := m.Get(k); strings.Join(v, ",") != strings.Join(e, ",") {
+			t.Errorf("bad group for key %d: expected %v, got %v", k, e, v)
+		}
+	}
+}
+
+func TestInvert(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 100; k++ {
+		m.Set(k, 99-k)
+	}
+	inv, err := intmap.Invert(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k := 0; k < 100; k++ {
+		if v, _ := inv.Get(99 - k); v != k {
+			t.Errorf("bad value for key %d: expected %d, got %d", 99-k, k, v)
+		}
+	}
+	m.Set(100, 0)
+	if _, err = intmap.Invert(&m); err == nil {
+		t.Error("Invert succeeded with duplicate values")
+	}
+}
+
+func TestKeysOf(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 100; k++ {
+		m.Set(k, k%10)
+	}
+	if !intmap.ContainsValue(&m, 9) || intmap.ContainsValue(&m, 10) {
+		t.Error("bad ContainsValue result")
+	}
+	ks := intmap.KeysOf(&m, 0)
+	sort.Ints(ks)
+	if len(ks) != 10 {
+		t.Fatalf("bad length: expected 10, got %d", len(ks))
+	}
+	for i, k := range ks {
+		if k != i*10 {
+			t.Errorf("bad key at %d: expected %d, got %d", i, i*10, k)
+		}
+	}
+}
+
+func TestMap_SetMany(t *testing.T) {
+	var m intmap.Map[int]
+	kvs := make([]intmap.KeyValue[int], 1000)
+	for i := range kvs {
+		kvs[i] = intmap.KeyValue[int]{Key: i, Value: -i}
+	}
+	m.SetMany(kvs)
+	if m.Len() != len(kvs) {
+		t.Fatalf("bad size: expected %d, got %d", len(kvs), m.Len())
+	}
+	for _, kv := range kvs {
+		if v, _ := m.Get(kv.Key); v != kv.Value {
+			t.Errorf("bad value for key %d: expected %d, got %d", kv.Key, kv.Value, v)
+		}
+	}
+}
+
+func TestMap_GetMany(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 100; k++ {
+		m.Set(k*2, -k*2)
+	}
+	keys := make([]int, 100)
+	for i := range keys {
+		keys[i] = i
+	}
+	out := make([]int, len(keys))
+	found := make([]bool, len(keys))
+	m.GetMany(keys, out, found)
+	for i, k := range keys {
+		if found[i] != (k%2 == 0) || out[i] != -k*(1-k%2) {
+			t.Errorf("bad result for key %d: %d, %v", k, out[i], found[i])
+		}
+	}
+	m.GetMany(keys, out, nil)
+}
+
+func TestMap_DeleteMany(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 100; k++ {
+		m.Set(k, k)
+	}
+	if n := m.DeleteMany([]int{0, 1, 2, 200, 2}); n != 3 {
+		t.Errorf("DeleteMany() = %d; expected 3", n)
+	}
+	if m.Len() != 97 {
+		t.Errorf("bad size: expected 97, got %d", m.Len())
+	}
+}
+
+func TestMap_CountFunc(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 100; k++ {
+		m.Set(k, k)
+	}
+	if n := m.CountFunc(func(k, v int) bool { return v%3 == 0 }); n != 34 {
+		t.Errorf("CountFunc() = %d; expected 34", n)
+	}
+}
+
+func benchmarkLargeMap(b *testing.B) (*intmap.Map[int], []int) {
+	const n = 1 << 22
+	var m intmap.Map[int]
+	m.Grow(n)
+	for i := 0; i < n; i++ {
+		m.Set(i, i)
+	}
+	r := rand.New(rand.NewSource(424242))
+	keys := make([]int, 1<<16)
+	for i := range keys {
+		keys[i] = r.Intn(n)
+	}
+	b.ResetTimer()
+	return &m, keys
+}
+
+func BenchmarkIntMapGetLoop(b *testing.B) {
+	m, keys := benchmarkLargeMap(b)
+	out := make([]int, len(keys))
+	for i := 0; i < b.N; i++ {
+		for j, k := range keys {
+			out[j], _ = m.Get(k)
+		}
+	}
+}
+
+func BenchmarkIntMapGetMany(b *testing.B) {
+	m, keys := benchmarkLargeMap(b)
+	out := make([]int, len(keys))
+	for i := 0; i < b.N; i++ {
+		m.GetMany(keys, out, nil)
+	}
+}
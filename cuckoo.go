@@ -0,0 +1,226 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+import (
+	"math/bits"
+	"math/rand/v2"
+)
+
+type cuckooSlot[V any] struct {
+	kv   KeyValue[V]
+	used bool
+}
+
+// CuckooMap is an integer keyed map using 2-choice cuckoo hashing: every key is
+// stored in one of two slots, one in each of two tables, given by two
+// independent hash functions. Lookups and deletions therefore look at no more
+// than two slots.
+//
+// Inserting a key in an occupied slot evicts the key it holds to its alternate
+// slot, possibly evicting another key, and so on. When an insertion loops for
+// too long, the map is rebuilt with new hash functions, or grown if it is more
+// than half full. Insertions are thus more expensive than with Map, and
+// occasionally very expensive.
+//
+// The zero key needs no special handling. The zero value is an empty map ready
+// to use. A CuckooMap must not be copied after first use.
+//
+type CuckooMap[V any] struct {
+	t1, t2 []cuckooSlot[V]
+	size   int
+	seed1  uint64
+	seed2  uint64
+}
+
+// NewCuckoo returns a new CuckooMap initialized with room for at least
+// capacity keys.
+//
+func NewCuckoo[V any](capacity int) *CuckooMap[V] {
+	var m CuckooMap[V]
+	m.Init(capacity)
+	return &m
+}
+
+// Init initializes the map with room for at least capacity keys. If the map
+// already contains data, it will be reset.
+//
+func (m *CuckooMap[V]) Init(capacity int) {
+	n := nextPowerOf2(capacity)
+	if n < 0 {
+		panic("invalid capacity requested")
+	}
+	if n < 4 {
+		n = 4
+	}
+	m.alloc(n)
+}
+
+// alloc allocates two empty tables of length n and picks new hash functions.
+//
+func (m *CuckooMap[V]) alloc(n int) {
+	m.t1 = make([]cuckooSlot[V], n)
+	m.t2 = make([]cuckooSlot[V], n)
+	m.size = 0
+	m.seed1 = rand.Uint64()
+	m.seed2 = rand.Uint64()
+}
+
+func (m *CuckooMap[V]) h1(key int) int {
+	return int(mix64(uint64(key)^m.seed1)) & (len(m.t1) - 1)
+}
+
+func (m *CuckooMap[V]) h2(key int) int {
+	x := uint64(key) * (m.seed2 | 1) // multiply-shift with a random odd multiplier
+	return int(x >> bits.LeadingZeros64(uint64(len(m.t2)-1)))
+}
+
+// find returns the slot holding key, or nil.
+//
+func (m *CuckooMap[V]) find(key int) *cuckooSlot[V] {
+	if len(m.t1) == 0 {
+		return nil
+	}
+	if s := &m.t1[m.h1(key)]; s.used && s.kv.Key == key {
+		return s
+	}
+	if s := &m.t2[m.h2(key)]; s.used && s.kv.Key == key {
+		return s
+	}
+	return nil
+}
+
+// Set sets the value for the given key.
+//
+func (m *CuckooMap[V]) Set(key int, value V) {
+	if s := m.find(key); s != nil {
+		s.kv.Value = value
+		return
+	}
+	if len(m.t1) == 0 {
+		m.Init(0)
+	} else if m.size >= len(m.t1) {
+		// 2-choice cuckoo hashing fails often above 50% load
+		m.rebuild(len(m.t1) << 1)
+	}
+	kv := KeyValue[V]{key, value}
+	for {
+		var ok bool
+		if kv, ok = m.insert(kv); ok {
+			return
+		}
+		// the evicted key is not in the map anymore; rebuild and retry.
+		m.rebuild(len(m.t1))
+	}
+}
+
+// insert inserts kv, whose key must not be in the map. If it gives up after
+// too many evictions, it returns the evicted key and false.
+//
+func (m *CuckooMap[V]) insert(kv KeyValue[V]) (KeyValue[V], bool) {
+	maxLoop := 8 + 2*bits.Len(uint(len(m.t1)))
+	for i := 0; i < maxLoop; i++ {
+		s := &m.t1[m.h1(kv.Key)]
+		if !s.used {
+			*s = cuckooSlot[V]{kv, true}
+			m.size++
+			return kv, true
+		}
+		s.kv, kv = kv, s.kv
+		s = &m.t2[m.h2(kv.Key)]
+		if !s.used {
+			*s = cuckooSlot[V]{kv, true}
+			m.size++
+			return kv, true
+		}
+		s.kv, kv = kv, s.kv
+	}
+	return kv, false
+}
+
+// rebuild rehashes all keys into tables of length n with new hash functions,
+// growing the tables further if needed.
+//
+func (m *CuckooMap[V]) rebuild(n int) {
+	t1, t2 := m.t1, m.t2
+	for tries := 0; ; tries++ {
+		if n < 0 {
+			panic("map size overflows addressable space")
+		}
+		m.alloc(n)
+		if m.insertAll(t1) && m.insertAll(t2) {
+			return
+		}
+		if tries > 0 {
+			n <<= 1
+		}
+	}
+}
+
+func (m *CuckooMap[V]) insertAll(t []cuckooSlot[V]) bool {
+	for i := range t {
+		if t[i].used {
+			if _, ok := m.insert(t[i].kv); !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Get returns the value associated with the given key and true, or the zero
+// value for V and false if the key is not set.
+//
+func (m *CuckooMap[V]) Get(key int) (v V, ok bool) {
+	if s := m.find(key); s != nil {
+		return s.kv.Value, true
+	}
+	return v, false
+}
+
+// Has returns true if the given key is set.
+//
+func (m *CuckooMap[V]) Has(key int) bool {
+	return m.find(key) != nil
+}
+
+// Delete deletes the given key and returns true if it was set.
+//
+func (m *CuckooMap[V]) Delete(key int) bool {
+	s := m.find(key)
+	if s == nil {
+		return false
+	}
+	*s = cuckooSlot[V]{}
+	m.size--
+	return true
+}
+
+// Clear removes all keys from the map. It keeps the allocated storage.
+//
+func (m *CuckooMap[V]) Clear() {
+	clear(m.t1)
+	clear(m.t2)
+	m.size = 0
+}
+
+// Len returns the number if keys set in the map.
+//
+func (m *CuckooMap[V]) Len() int {
+	return m.size
+}
+
+// Range calls f sequentially for each key and value present in the map. If f
+// returns false, Range stops the iteration. f must not modify the map.
+//
+func (m *CuckooMap[V]) Range(f func(key int, value V) bool) {
+	for _, t := range [...][]cuckooSlot[V]{m.t1, m.t2} {
+		for i := range t {
+			if s := &t[i]; s.used && !f(s.kv.Key, s.kv.Value) {
+				return
+			}
+		}
+	}
+}
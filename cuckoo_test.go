@@ -0,0 +1,63 @@
+package intmap_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/db47h/intmap"
+)
+
+func TestCuckooMap_Growth(t *testing.T) {
+	var m intmap.CuckooMap[int]
+	for k := 0; k < 10000; k++ {
+		m.Set(k, k)
+		// tables are grown before they are more than half full
+		if _, _, n := intmap.CuckooSlots(&m, k); m.Len() > n {
+			t.Fatalf("%d keys in two tables of %d slots", m.Len(), n)
+		}
+	}
+	for k := 0; k < 10000; k++ {
+		if v, ok := m.Get(k); !ok || v != k {
+			t.Fatalf("bad value for key %d: %d, %v", k, v, ok)
+		}
+	}
+}
+
+func TestCuckooMap_Rebuild(t *testing.T) {
+	m := intmap.NewCuckoo[int](16)
+	// three keys with the same slots in both tables cannot all be placed: the
+	// map must be rebuilt with new hash functions.
+	var keys []int
+	i1, i2, _ := intmap.CuckooSlots(m, 1)
+	for k := 1; len(keys) < 3; k++ {
+		if j1, j2, _ := intmap.CuckooSlots(m, k); j1 == i1 && j2 == i2 {
+			keys = append(keys, k)
+		}
+	}
+	for _, k := range keys {
+		m.Set(k, -k)
+	}
+	if m.Len() != len(keys) {
+		t.Fatalf("Len() = %d, expected %d", m.Len(), len(keys))
+	}
+	for _, k := range keys {
+		if v, ok := m.Get(k); !ok || v != -k {
+			t.Errorf("bad value for key %d: %d, %v", k, v, ok)
+		}
+	}
+}
+
+func BenchmarkCuckooMapGet(b *testing.B) {
+	var m intmap.CuckooMap[Value]
+	for i := 0; i < *keyMax; i++ {
+		m.Set(i, Value(i))
+	}
+	rand.Seed(424242)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v, ok := m.Get(rand.Intn(*keyMax))
+		if ok {
+			result = v
+		}
+	}
+}
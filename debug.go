@@ -0,0 +1,43 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// DebugDump writes the layout of the map's backing array to w, one slot per
+// line. Used slots show their key, home slot and displacement, that is the
+// probe length of the key (see Stats); free slots are shown as "-" and
+// tombstones as "x". Values are not written.
+//
+//	len 3, cap 8, threshold 7, free key: false
+//	0: -
+//	1: key 22, home 1, disp 0
+//	2: key 42, home 1, disp 1
+//	...
+//
+// The output format is meant for humans and may change.
+//
+func (m *Map[V]) DebugDump(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "len %d, cap %d, threshold %d, free key: %v\n", m.Len(), m.Cap(), m.threshold, m.hasFreeKey)
+	mod := len(m.es) - 1
+	for i := range m.es {
+		k := m.es[i].Key
+		if k == m.free {
+			if m.isTomb(i) {
+				fmt.Fprintf(bw, "%d: x\n", i)
+			} else {
+				fmt.Fprintf(bw, "%d: -\n", i)
+			}
+			continue
+		}
+		fmt.Fprintf(bw, "%d: key %d, home %d, disp %d\n", i, k, m.slot(k, mod), m.probeLen(i, mod))
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,40 @@
+package intmap_test
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/db47h/intmap"
+)
+
+func TestMap_DebugDump(t *testing.T) {
+	m := intmap.New[int](16, 0.875)
+	m.SetSeed(0)
+	for k := 0; k < 10; k++ {
+		m.Set(k*16, k)
+	}
+	var sb strings.Builder
+	if err := m.DebugDump(&sb); err != nil {
+		t.Fatal(err)
+	}
+	s := bufio.NewScanner(strings.NewReader(sb.String()))
+	if !s.Scan() || s.Text() != "len 10, cap 16, threshold 14, free key: true" {
+		t.Fatalf("bad header: %q", s.Text())
+	}
+	lines, keys := 0, 0
+	for s.Scan() {
+		var i, k, home, disp int
+		if n, _ := fmt.Sscanf(s.Text(), "%d: key %d, home %d, disp %d", &i, &k, &home, &disp); n == 4 {
+			if (home+disp)&15 != i {
+				t.Errorf("bad slot line: %q", s.Text())
+			}
+			keys++
+		}
+		lines++
+	}
+	if lines != 16 || keys != 9 {
+		t.Errorf("got %d lines and %d keys, expected 16 and 9", lines, keys)
+	}
+}
@@ -0,0 +1,316 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+import (
+	"math"
+	"math/bits"
+)
+
+const (
+	denseMinSpan  = 64 // dense storage is always allowed for spans up to denseMinSpan
+	denseMaxRatio = 4  // maximum ratio of span to number of keys when growing
+)
+
+// DenseMap is an integer keyed map for keys that fall in a compact range, like
+// entity IDs. As long as the keys are dense enough, values are stored in a
+// plain slice indexed by key - lo, where lo is the lowest key of the range, and
+// a bitmap records which keys are present. Lookups then need no hashing nor
+// probing at all.
+//
+// When a new key would make the range more than 4 times as large as the number
+// of keys, or when deletions bring the density under 1/8th, the map falls back
+// to a hash table. While hashed, the map keeps track of its key range and
+// migrates back to dense storage once the keys are at least half as many as the
+// range is large. The range is checked after a number of insertions or
+// deletions proportional to the map size, so that switching costs amortized
+// O(1) per operation. Clear resets a DenseMap to dense storage.
+//
+// The zero value is an empty map ready to use. A DenseMap must not be copied
+// after first use.
+//
+type DenseMap[V any] struct {
+	lo      int
+	vs      []V
+	present []uint64
+	n       int
+	hashed  bool // keys are stored in h
+	h       Map[V]
+	check   int // number of operations before the next density check in hash mode
+}
+
+// IsDense reports whether the map currently uses dense storage.
+//
+func (m *DenseMap[V]) IsDense() bool {
+	return !m.hashed
+}
+
+// inRange reports whether key can be stored in the dense array without
+// extending it.
+//
+func (m *DenseMap[V]) inRange(key int) bool {
+	return key >= m.lo && uint(key-m.lo) < uint(len(m.vs))
+}
+
+// Set sets the value for the given key.
+//
+func (m *DenseMap[V]) Set(key int, value V) {
+	if m.hashed {
+		if _, existed := m.h.Swap(key, value); !existed {
+			m.checkDensity()
+		}
+		return
+	}
+	if !m.inRange(key) && !m.extend(key) {
+		m.toHash()
+		m.h.Set(key, value)
+		return
+	}
+	i := uint(key - m.lo)
+	w, b := i/64, uint64(1)<<(i%64)
+	if m.present[w]&b == 0 {
+		m.present[w] |= b
+		m.n++
+	}
+	m.vs[i] = value
+}
+
+// extend grows the dense array so that it can hold key. It returns false if
+// the resulting array would be too sparse.
+//
+func (m *DenseMap[V]) extend(key int) bool {
+	if m.n == 0 {
+		if len(m.vs) == 0 {
+			m.vs = make([]V, denseMinSpan)
+			m.present = make([]uint64, denseMinSpan/64)
+		}
+		m.lo = key
+		if m.lo > math.MaxInt-len(m.vs)+1 {
+			m.lo = math.MaxInt - len(m.vs) + 1
+		}
+		return true
+	}
+	lo, hi := m.bounds()
+	if key < lo {
+		lo = key
+	} else {
+		hi = key
+	}
+	limit := uint(denseMaxRatio * (m.n + 1))
+	if limit < denseMinSpan {
+		limit = denseMinSpan
+	}
+	span := uint(hi-lo) + 1
+	if span == 0 || span > limit {
+		return false
+	}
+	l := 2 * uint(len(m.vs))
+	if l < span {
+		l = span
+	}
+	if l > limit {
+		l = limit
+	}
+	l = (l + 63) &^ 63
+	if key < m.lo {
+		// leave room below for keys inserted in descending order
+		if slack := int(l - span); lo >= math.MinInt+slack {
+			lo -= slack
+		} else {
+			lo = math.MinInt
+		}
+	}
+	vs := make([]V, l)
+	present := make([]uint64, l/64)
+	off := uint(m.lo - lo) // may wrap around if lo > m.lo, which is fine
+	for w, word := range m.present {
+		for word != 0 {
+			i := uint(w*64 + bits.TrailingZeros64(word))
+			word &= word - 1
+			j := off + i
+			present[j/64] |= 1 << (j % 64)
+			vs[j] = m.vs[i]
+		}
+	}
+	m.lo, m.vs, m.present = lo, vs, present
+	return true
+}
+
+// bounds returns the lowest and highest keys in the dense array, which must
+// not be empty.
+//
+func (m *DenseMap[V]) bounds() (lo, hi int) {
+	w := 0
+	for m.present[w] == 0 {
+		w++
+	}
+	lo = m.lo + w*64 + bits.TrailingZeros64(m.present[w])
+	w = len(m.present) - 1
+	for m.present[w] == 0 {
+		w--
+	}
+	hi = m.lo + w*64 + 63 - bits.LeadingZeros64(m.present[w])
+	return lo, hi
+}
+
+// toHash moves all keys to the hash table.
+//
+func (m *DenseMap[V]) toHash() {
+	m.h.Init(m.n*2, defaultFillRatio)
+	m.Range(func(key int, value V) bool {
+		m.h.Set(key, value)
+		return true
+	})
+	m.vs, m.present, m.n = nil, nil, 0
+	m.hashed = true
+	m.check = m.h.Len()/2 + 1
+}
+
+// checkDensity counts one insertion or deletion in hash mode and, every
+// m.check operations, moves the keys back to dense storage if their range has
+// become dense enough.
+//
+func (m *DenseMap[V]) checkDensity() {
+	if m.check--; m.check > 0 {
+		return
+	}
+	n := m.h.Len()
+	m.check = n/2 + 1
+	if n == 0 {
+		m.toDense(0, 0)
+		return
+	}
+	lo, _ := m.h.MinKey()
+	hi, _ := m.h.MaxKey()
+	if span := uint(hi-lo) + 1; span != 0 && span <= uint(2*n) {
+		m.toDense(lo, span)
+	}
+}
+
+// toDense moves all keys from the hash table to a new dense array starting at
+// lo and holding at least span keys.
+//
+func (m *DenseMap[V]) toDense(lo int, span uint) {
+	l := (span + 63) &^ 63
+	if l < denseMinSpan {
+		l = denseMinSpan
+	}
+	if lo > math.MaxInt-int(l)+1 {
+		lo = math.MaxInt - int(l) + 1
+	}
+	m.lo = lo
+	m.vs = make([]V, l)
+	m.present = make([]uint64, l/64)
+	m.n = 0
+	m.hashed = false
+	m.h.Range(func(key int, value V) bool {
+		i := uint(key - m.lo)
+		m.present[i/64] |= 1 << (i % 64)
+		m.vs[i] = value
+		m.n++
+		return true
+	})
+	m.h = Map[V]{}
+}
+
+// Get returns the value associated with the given key and true, or the zero
+// value for V and false if the key is not set.
+//
+func (m *DenseMap[V]) Get(key int) (v V, ok bool) {
+	if m.hashed {
+		return m.h.Get(key)
+	}
+	if !m.inRange(key) {
+		return v, false
+	}
+	i := uint(key - m.lo)
+	if m.present[i/64]&(1<<(i%64)) == 0 {
+		return v, false
+	}
+	return m.vs[i], true
+}
+
+// Has returns true if the given key is set.
+//
+func (m *DenseMap[V]) Has(key int) bool {
+	if m.hashed {
+		return m.h.Has(key)
+	}
+	if !m.inRange(key) {
+		return false
+	}
+	i := uint(key - m.lo)
+	return m.present[i/64]&(1<<(i%64)) != 0
+}
+
+// Delete deletes the given key and returns true if it was set.
+//
+func (m *DenseMap[V]) Delete(key int) bool {
+	if m.hashed {
+		if !m.h.Delete(key) {
+			return false
+		}
+		m.checkDensity()
+		return true
+	}
+	if !m.inRange(key) {
+		return false
+	}
+	i := uint(key - m.lo)
+	w, b := i/64, uint64(1)<<(i%64)
+	if m.present[w]&b == 0 {
+		return false
+	}
+	var zero V
+	m.present[w] &^= b
+	m.vs[i] = zero
+	m.n--
+	if len(m.vs) > denseMinSpan && len(m.vs) > 2*denseMaxRatio*m.n {
+		m.toHash()
+	}
+	return true
+}
+
+// Clear removes all keys from the map and switches it back to dense storage.
+//
+func (m *DenseMap[V]) Clear() {
+	if m.hashed {
+		m.h = Map[V]{}
+		m.hashed = false
+	}
+	clear(m.vs)
+	clear(m.present)
+	m.n = 0
+}
+
+// Len returns the number if keys set in the map.
+//
+func (m *DenseMap[V]) Len() int {
+	if m.hashed {
+		return m.h.Len()
+	}
+	return m.n
+}
+
+// Range calls f sequentially for each key and value present in the map. If f
+// returns false, Range stops the iteration. f must not modify the map.
+//
+// With dense storage, keys are visited in ascending order.
+//
+func (m *DenseMap[V]) Range(f func(key int, value V) bool) {
+	if m.hashed {
+		m.h.Range(f)
+		return
+	}
+	for w, word := range m.present {
+		for word != 0 {
+			i := w*64 + bits.TrailingZeros64(word)
+			word &= word - 1
+			if !f(m.lo+i, m.vs[i]) {
+				return
+			}
+		}
+	}
+}
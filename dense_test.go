@@ -0,0 +1,103 @@
+package intmap_test
+
+import (
+	"testing"
+
+	"github.com/db47h/intmap"
+)
+
+func TestDenseMap(t *testing.T) {
+	var m intmap.DenseMap[int]
+	const n = 1000
+	// descending order to exercise downward extension
+	for i := n - 1; i >= 0; i-- {
+		m.Set(i+500, i)
+	}
+	if !m.IsDense() {
+		t.Fatal("contiguous keys not stored densely")
+	}
+	if m.Len() != n {
+		t.Fatalf("Len() = %d, expected %d", m.Len(), n)
+	}
+	for i := 0; i < n; i++ {
+		if v, ok := m.Get(i + 500); !ok || v != i {
+			t.Errorf("bad value for key %d: %d, %v", i+500, v, ok)
+		}
+	}
+	prev := 0
+	m.Range(func(k, v int) bool {
+		if k <= prev {
+			t.Errorf("key %d visited after %d", k, prev)
+		}
+		prev = k
+		return true
+	})
+	m.Set(1<<30, -1)
+	if m.IsDense() {
+		t.Fatal("sparse keys stored densely")
+	}
+	if v, ok := m.Get(1 << 30); !ok || v != -1 || m.Len() != n+1 {
+		t.Fatalf("bad value after switch to hashing: %d, %v", v, ok)
+	}
+	for i := 0; i < n; i++ {
+		if v, ok := m.Get(i + 500); !ok || v != i {
+			t.Errorf("bad value for key %d after switch to hashing: %d, %v", i+500, v, ok)
+		}
+	}
+
+	m.Clear()
+	for i := 0; i < n; i++ {
+		m.Set(i, i)
+	}
+	for i := 0; i < n; i++ {
+		if i%16 != 0 && !m.Delete(i) {
+			t.Errorf("Delete(%d) returned false", i)
+		}
+	}
+	if m.IsDense() {
+		t.Error("sparse map still stored densely after deletions")
+	}
+	if m.Len() != n/16+1 {
+		t.Errorf("Len() = %d, expected %d", m.Len(), n/16+1)
+	}
+	for i := 0; i < n; i++ {
+		if m.Has(i) != (i%16 == 0) {
+			t.Errorf("Has(%d) = %v", i, !(i%16 == 0))
+		}
+	}
+}
+
+func TestDenseMap_adaptive(t *testing.T) {
+	var m intmap.DenseMap[int]
+	const n = 1000
+	for i := 0; i < n; i++ {
+		m.Set(i*1000, i)
+	}
+	if m.IsDense() {
+		t.Fatal("sparse keys stored densely")
+	}
+	// fill in the gaps of the lower range, then remove the outliers
+	for i := 0; i < 2*n; i++ {
+		m.Set(i, i)
+	}
+	for i := 2; i < n; i++ {
+		m.Delete(i * 1000)
+	}
+	// density is checked after a number of operations proportional to the
+	// map size.
+	for i := 0; i < 2*n && !m.IsDense(); i++ {
+		m.Set(2*n, 0)
+		m.Delete(2 * n)
+	}
+	if !m.IsDense() {
+		t.Fatal("dense keys still hashed")
+	}
+	if m.Len() != 2*n {
+		t.Fatalf("Len() = %d, expected %d", m.Len(), 2*n)
+	}
+	for i := 0; i < 2*n; i++ {
+		if v, ok := m.Get(i); !ok || v != i {
+			t.Errorf("bad value for key %d: %d, %v", i, v, ok)
+		}
+	}
+}
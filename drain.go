@@ -0,0 +1,35 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+import "context"
+
+// Drain starts a goroutine that removes key-value pairs from the map and sends
+// them over the returned channel, in an unspecified order. The channel is
+// closed once the map is empty or ctx is cancelled, whichever comes first. On
+// cancellation, entries not yet received are left in the map.
+//
+// The map must not be accessed by any other goroutine until the channel is
+// closed.
+//
+func (m *Map[V]) Drain(ctx context.Context) <-chan KeyValue[V] {
+	ch := make(chan KeyValue[V])
+	go func() {
+		defer close(ch)
+		for {
+			k, v, ok := m.Pop()
+			if !ok {
+				return
+			}
+			select {
+			case ch <- KeyValue[V]{k, v}:
+			case <-ctx.Done():
+				m.Set(k, v)
+				return
+			}
+		}
+	}()
+	return ch
+}
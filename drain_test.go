@@ -0,0 +1,41 @@
+package intmap_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/db47h/intmap"
+)
+
+func TestMap_Drain(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 100; k++ {
+		m.Set(k, -k)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := m.Drain(ctx)
+	n := 0
+	for kv := range ch {
+		if kv.Value != -kv.Key {
+			t.Errorf("bad entry %v", kv)
+		}
+		if n++; n == 10 {
+			cancel()
+			break
+		}
+	}
+	// wait for the draining goroutine to exit
+	for range ch {
+		n++
+	}
+	if m.Len() != 100-n {
+		t.Errorf("bad size after cancel: expected %d, got %d", 100-n, m.Len())
+	}
+	for range m.Drain(context.Background()) {
+		n++
+	}
+	if n != 100 || m.Len() != 0 {
+		t.Errorf("bad counts: %d received, %d left", n, m.Len())
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+// Entry is a handle on a single map slot, either occupied or vacant, as
+// returned by Map.Entry.
+//
+// An Entry is only valid until the next modification of the map by any other
+// means than the Entry itself.
+//
+type Entry[V any] struct {
+	m     *Map[V]
+	key   int
+	idx   int
+	found bool
+}
+
+// Entry looks up the given key and returns an Entry for in-place manipulation.
+//
+//	m.Entry(k).AndModify(func(v *int) { *v++ }).OrInsert(1)
+//
+func (m *Map[V]) Entry(key int) Entry[V] {
+	if key == m.free {
+		return Entry[V]{m: m, key: key, found: m.hasFreeKey}
+	}
+	idx, found := m.probe(key)
+	return Entry[V]{m: m, key: key, idx: idx, found: found}
+}
+
+// Key returns the entry key.
+//
+func (e Entry[V]) Key() int {
+	return e.key
+}
+
+// Exists returns true if the key is present in the map.
+//
+func (e Entry[V]) Exists() bool {
+	return e.found
+}
+
+// OrInsert sets the entry value to v if the key does not exist and returns a
+// pointer to the value in the map. The pointer is invalidated by any
+// subsequent insertion or deletion in the map.
+//
+func (e Entry[V]) OrInsert(v V) *V {
+	m := e.m
+	if e.key == m.free {
+		if !e.found {
+			m.setFreeKey(v)
+		}
+		return &m.freeKeyValue
+	}
+	if !e.found {
+		e.idx = m.insertAt(e.key, e.idx)
+		m.es[e.idx].Value = v
+	}
+	return &m.es[e.idx].Value
+}
+
+// AndModify calls f with a pointer to the entry value if the key exists. It
+// returns e to allow call chaining.
+//
+func (e Entry[V]) AndModify(f func(v *V)) Entry[V] {
+	switch {
+	case !e.found:
+	case e.key == e.m.free:
+		f(&e.m.freeKeyValue)
+	default:
+		f(&e.m.es[e.idx].Value)
+	}
+	return e
+}
+
+// Delete deletes the entry key from the map and returns true if it was
+// present.
+//
+func (e Entry[V]) Delete() bool {
+	if !e.found {
+		return false
+	}
+	if e.key == e.m.free {
+		return e.m.Delete(e.m.free)
+	}
+	e.m.remove(e.idx)
+	return true
+}
@@ -0,0 +1,32 @@
+package intmap_test
+
+import (
+	"testing"
+
+	"github.com/db47h/intmap"
+)
+
+func TestMap_Entry(t *testing.T) {
+	var m intmap.Map[int]
+	inc := func(v *int) { *v++ }
+
+	for i := 0; i < 10; i++ {
+		for k := 0; k < 100; k++ {
+			m.Entry(k).AndModify(inc).OrInsert(1)
+		}
+	}
+	for k := 0; k < 100; k++ {
+		if v, _ := m.Get(k); v != 10 {
+			t.Errorf("bad value for key %d: expected 10, got %d", k, v)
+		}
+		if e := m.Entry(k); !e.Exists() || !e.Delete() {
+			t.Errorf("failed to delete key %d", k)
+		}
+	}
+	if m.Len() != 0 {
+		t.Errorf("bad size: expected 0, got %d", m.Len())
+	}
+	if m.Entry(42).Delete() {
+		t.Error("Delete succeeded on missing key")
+	}
+}
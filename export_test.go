@@ -0,0 +1,38 @@
+package intmap
+
+// HopscotchHome returns the home slot of key in m.
+func HopscotchHome[V any](m *HopscotchMap[V], key int) int {
+	return m.home(key)
+}
+
+// OccupancySeed returns the hash seed of m.
+func OccupancySeed[V any](m *OccupancyMap[V]) uint64 {
+	return m.seed
+}
+
+// Group probing functions, and their SWAR versions.
+var (
+	MatchH2                 = matchH2
+	MatchEmpty              = matchEmpty
+	MatchEmptyOrDeleted     = matchEmptyOrDeleted
+	SWARMatchH2             = swarMatchH2
+	SWARMatchEmpty          = swarMatchEmpty
+	SWARMatchEmptyOrDeleted = swarMatchEmptyOrDeleted
+)
+
+// SwissGroup returns the first group probed for key in m.
+func SwissGroup[V any](m *SwissMap[V], key int) int {
+	h1, _ := m.hash(key)
+	return int(h1 & uint(len(m.ctrl)/groupSize-1))
+}
+
+// SwissCtrl returns the control bytes of m.
+func SwissCtrl[V any](m *SwissMap[V]) []byte {
+	return m.ctrl
+}
+
+// CuckooSlots returns the slots of key in both tables of m, and the length of
+// the tables.
+func CuckooSlots[V any](m *CuckooMap[V], key int) (i1, i2, n int) {
+	return m.h1(key), m.h2(key), len(m.t1)
+}
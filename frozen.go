@@ -0,0 +1,200 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+import (
+	"math/rand/v2"
+	"sort"
+)
+
+// FrozenMap is a read-only integer keyed map built with BuildPerfect. It uses a
+// perfect hash function so that every key has its own slot and lookups never
+// probe: a Get reads one displacement from a small array, typically in cache,
+// and then exactly one slot.
+//
+type FrozenMap[V any] struct {
+	seed uint64
+	disp []uint32
+	es   []KeyValue[V]
+	size int
+}
+
+// mix64 is the finalizer of the SplitMix64 generator.
+//
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// bucket returns the bucket of a key with hash h among n buckets.
+//
+func bucket(h uint64, n int) int {
+	return int((h >> 32) * uint64(n) >> 32)
+}
+
+// buildMaxTries is the number of seeds tried by BuildPerfect before doubling the
+// table size.
+//
+const buildMaxTries = 4
+
+// BuildPerfect returns a FrozenMap holding the given entries. If several
+// entries have the same key, the last one wins.
+//
+// The perfect hash function is built with the CHD algorithm: keys are first
+// hashed into buckets of about 4 keys, then, from the largest bucket to the
+// smallest, a displacement is searched for each bucket such that all its keys
+// land in free slots of a table with a load factor between 40% and 80%. This
+// takes linear time on average. In the unlikely event that no displacement is
+// found after a few seeds, the table size is doubled.
+//
+func BuildPerfect[V any](entries []KeyValue[V]) *FrozenMap[V] {
+	var idx Map[int]
+	idx.Grow(len(entries))
+	kvs := make([]KeyValue[V], 0, len(entries))
+	for _, e := range entries {
+		if i, ok := idx.Get(e.Key); ok {
+			kvs[i].Value = e.Value
+			continue
+		}
+		idx.Set(e.Key, len(kvs))
+		kvs = append(kvs, e)
+	}
+
+	m := &FrozenMap[V]{size: len(kvs)}
+	if len(kvs) == 0 {
+		return m
+	}
+	// A full table leaves the last buckets a single choice of slots, which no
+	// displacement is likely to hit.
+	l := nextPowerOf2((len(kvs)*5 + 3) / 4)
+	nb := (len(kvs) + 3) / 4
+	for tries := 1; !m.build(kvs, l, nb); tries++ {
+		if tries%buildMaxTries == 0 {
+			l <<= 1
+		}
+	}
+	return m
+}
+
+// build tries to build the perfect hash function with a new seed, l slots and
+// nb buckets.
+//
+func (m *FrozenMap[V]) build(kvs []KeyValue[V], l, nb int) bool {
+	const maxDisp = 1 << 16
+
+	m.seed = rand.Uint64()
+	buckets := make([][]int, nb) // indices in kvs
+	for i := range kvs {
+		b := bucket(mix64(uint64(kvs[i].Key)^m.seed), nb)
+		buckets[b] = append(buckets[b], i)
+	}
+	order := make([]int, nb)
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return len(buckets[order[i]]) > len(buckets[order[j]]) })
+
+	mask := uint64(l - 1)
+	used := make([]bool, l)
+	slots := make([]int, 0, 16)
+	m.disp = make([]uint32, nb)
+	m.es = make([]KeyValue[V], l)
+	for _, b := range order {
+		bk := buckets[b]
+		if len(bk) == 0 {
+			break
+		}
+	search:
+		for d := uint32(0); ; d++ {
+			if d == maxDisp {
+				return false
+			}
+			slots = slots[:0]
+			for _, i := range bk {
+				s := int(mix64(mix64(uint64(kvs[i].Key)^m.seed)+uint64(d)) & mask)
+				if used[s] {
+					continue search
+				}
+				for _, t := range slots {
+					if t == s {
+						continue search
+					}
+				}
+				slots = append(slots, s)
+			}
+			m.disp[b] = d
+			for j, i := range bk {
+				used[slots[j]] = true
+				m.es[slots[j]] = kvs[i]
+			}
+			break
+		}
+	}
+	// A free slot holds a key that is in the map, and therefore hashes to
+	// another slot, so that lookups landing there fail.
+	for s := range m.es {
+		if !used[s] {
+			m.es[s].Key = kvs[0].Key
+		}
+	}
+	return true
+}
+
+// slot returns the slot for key.
+//
+func (m *FrozenMap[V]) slot(key int) int {
+	h := mix64(uint64(key) ^ m.seed)
+	d := m.disp[bucket(h, len(m.disp))]
+	return int(mix64(h+uint64(d)) & uint64(len(m.es)-1))
+}
+
+// Get returns the value associated with the given key and true, or the zero
+// value for V and false if the key is not set.
+//
+func (m *FrozenMap[V]) Get(key int) (v V, ok bool) {
+	if m.size == 0 {
+		return v, false
+	}
+	if e := &m.es[m.slot(key)]; e.Key == key {
+		return e.Value, true
+	}
+	return v, false
+}
+
+// Has returns true if the given key is set.
+//
+func (m *FrozenMap[V]) Has(key int) bool {
+	return m.size > 0 && m.es[m.slot(key)].Key == key
+}
+
+// Len returns the number if keys set in the map.
+//
+func (m *FrozenMap[V]) Len() int {
+	return m.size
+}
+
+// Cap returns the capacity of the map, that is the number of slots in its
+// backing array.
+//
+func (m *FrozenMap[V]) Cap() int {
+	return len(m.es)
+}
+
+// Range calls f sequentially for each key and value present in the map. If f
+// returns false, Range stops the iteration.
+//
+func (m *FrozenMap[V]) Range(f func(key int, value V) bool) {
+	if m.size == 0 {
+		return
+	}
+	for s := range m.es {
+		if e := &m.es[s]; m.slot(e.Key) == s && !f(e.Key, e.Value) {
+			return
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package intmap_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/db47h/intmap"
+)
+
+func TestBuildPerfect(t *testing.T) {
+	r := rand.New(rand.NewSource(424242))
+	sm := map[int]int{0: 42}
+	kvs := []intmap.KeyValue[int]{{0, 42}}
+	for i := 0; i < 10000; i++ {
+		k := r.Int()
+		sm[k] = i
+		kvs = append(kvs, intmap.KeyValue[int]{k, i})
+	}
+	// duplicate key, last one wins
+	kvs = append(kvs, intmap.KeyValue[int]{0, 1337})
+	sm[0] = 1337
+
+	m := intmap.BuildPerfect(kvs)
+	if m.Len() != len(sm) {
+		t.Fatalf("Len() = %d, expected %d", m.Len(), len(sm))
+	}
+	for k, v := range sm {
+		if vv, ok := m.Get(k); !ok || vv != v {
+			t.Fatalf("bad value for key %d: %d, %v; expected %d", k, vv, ok, v)
+		}
+	}
+	for i := 0; i < 10000; i++ {
+		k := r.Int()
+		if _, exp := sm[k]; m.Has(k) != exp {
+			t.Fatalf("Has(%d) = %v", k, !exp)
+		}
+	}
+	cnt := 0
+	m.Range(func(k, v int) bool {
+		if sm[k] != v {
+			t.Errorf("bad value for key %d: %d", k, v)
+		}
+		cnt++
+		return true
+	})
+	if cnt != len(sm) {
+		t.Errorf("Range visited %d keys, expected %d", cnt, len(sm))
+	}
+
+	e := intmap.BuildPerfect[int](nil)
+	if e.Has(0) || e.Len() != 0 {
+		t.Error("empty FrozenMap has keys")
+	}
+}
+
+func TestBuildPerfect_PowerOfTwo(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	for _, n := range []int{1, 2, 4, 1 << 10, 1 << 20} {
+		kvs := make([]intmap.KeyValue[int], n)
+		for i, k := range r.Perm(n) {
+			kvs[i] = intmap.KeyValue[int]{Key: k*7919 + 1, Value: i}
+		}
+		m := intmap.BuildPerfect(kvs)
+		if m.Len() != n {
+			t.Fatalf("%d keys: Len() = %d", n, m.Len())
+		}
+		for _, kv := range kvs {
+			if v, ok := m.Get(kv.Key); !ok || v != kv.Value {
+				t.Fatalf("%d keys: bad value for key %d: %d, %v", n, kv.Key, v, ok)
+			}
+		}
+	}
+}
+
+func TestBuildPerfect_LoadFactor(t *testing.T) {
+	for n := 1; n <= 64; n++ {
+		kvs := make([]intmap.KeyValue[int], n)
+		for i := range kvs {
+			kvs[i] = intmap.KeyValue[int]{Key: i*7919 + 1, Value: i}
+		}
+		m := intmap.BuildPerfect(kvs)
+		// the table is only doubled if no perfect hash function is found
+		if lf := float64(n) / float64(m.Cap()); lf > 0.8 {
+			t.Errorf("%d keys: load factor %.3f with %d slots", n, lf, m.Cap())
+		}
+	}
+}
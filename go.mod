@@ -1,3 +1,3 @@
 module github.com/db47h/intmap
 
-go 1.18
+go 1.23
@@ -0,0 +1,250 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+import (
+	"math/bits"
+	"math/rand/v2"
+)
+
+// hopRange is the size of the neighborhood of a slot in a HopscotchMap.
+//
+const hopRange = 16
+
+type hopSlot[V any] struct {
+	kv   KeyValue[V]
+	used bool
+	hop  uint16 // bit i is set if slot+i holds a key whose home slot is this one
+}
+
+// HopscotchMap is an integer keyed map using hopscotch hashing: every key is
+// stored within 16 slots of its home slot, and each slot keeps a bitmap of the
+// neighboring slots that hold keys with this home slot. Lookups only look at
+// the slots of this bitmap, which bounds their cost even at fill ratios above
+// 90%, while insertions may need to move keys around to bring a free slot
+// into the neighborhood. If that fails, the map is grown regardless of its
+// fill ratio, or rehashed with a new seed if it is no more than half full.
+//
+// The zero key needs no special handling. The zero value is an empty map ready
+// to use, with a fill ratio of 87.5%. A HopscotchMap must not be copied after
+// first use.
+//
+type HopscotchMap[V any] struct {
+	slots     []hopSlot[V]
+	size      int
+	threshold int
+	seed      uint
+}
+
+// NewHopscotch returns a new HopscotchMap initialized with the given starting
+// capacity and fill ratio.
+//
+// See Map.Init for more details about the capacity and fillratio parameters.
+//
+func NewHopscotch[V any](capacity int, fillratio float32) *HopscotchMap[V] {
+	var m HopscotchMap[V]
+	m.Init(capacity, fillratio)
+	return &m
+}
+
+// Init initializes the map with the given initial capacity and fill ratio. If
+// the map already contains data, it will be reset.
+//
+// See Map.Init for more details about the capacity and fillratio parameters.
+//
+func (m *HopscotchMap[V]) Init(capacity int, fillratio float32) {
+	capacity = nextPowerOf2(capacity)
+	if capacity < 0 {
+		panic("invalid capacity requested")
+	}
+	if capacity < 2 {
+		capacity = 2
+	}
+	m.slots = make([]hopSlot[V], capacity)
+	m.size = 0
+	m.threshold = thresholdFor(capacity, fillratio)
+	m.seed = uint(rand.Uint64())
+}
+
+func (m *HopscotchMap[V]) home(key int) int {
+	return int(mix64(uint64(key)^uint64(m.seed))) & (len(m.slots) - 1)
+}
+
+// find returns the index of the slot holding key, or -1 if there is none.
+//
+func (m *HopscotchMap[V]) find(key int) int {
+	if len(m.slots) == 0 {
+		return -1
+	}
+	mod := len(m.slots) - 1
+	home := m.home(key)
+	for b := uint(m.slots[home].hop); b != 0; b &= b - 1 {
+		i := (home + bits.TrailingZeros(b)) & mod
+		if m.slots[i].kv.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// Set sets the value for the given key.
+//
+func (m *HopscotchMap[V]) Set(key int, value V) {
+	if i := m.find(key); i >= 0 {
+		m.slots[i].kv.Value = value
+		return
+	}
+	if len(m.slots) == 0 {
+		m.Init(8, defaultFillRatio)
+	} else if m.size >= m.threshold {
+		m.resize()
+	}
+	for !m.insert(KeyValue[V]{key, value}) {
+		m.resize()
+	}
+}
+
+// insert inserts kv, whose key must not be in the map. It returns false if no
+// free slot can be moved into the neighborhood of its home slot.
+//
+func (m *HopscotchMap[V]) insert(kv KeyValue[V]) bool {
+	mod := len(m.slots) - 1
+	home := m.home(kv.Key)
+	// find a free slot
+	free, d := home, 0
+	for m.slots[free].used {
+		if d++; d > mod {
+			return false
+		}
+		free = (free + 1) & mod
+	}
+	// move it closer to home
+	for d >= hopRange {
+		moved := false
+		for b := (free - hopRange + 1) & mod; b != free && !moved; b = (b + 1) & mod {
+			dist := (free - b) & mod
+			for h := uint(m.slots[b].hop); h != 0; h &= h - 1 {
+				k := bits.TrailingZeros(h)
+				if k >= dist {
+					break
+				}
+				i := (b + k) & mod
+				m.slots[free].kv, m.slots[free].used = m.slots[i].kv, true
+				m.slots[b].hop |= 1 << dist
+				m.slots[b].hop &^= 1 << k
+				m.slots[i].kv, m.slots[i].used = KeyValue[V]{}, false
+				d -= (free - i) & mod
+				free = i
+				moved = true
+				break
+			}
+		}
+		if !moved {
+			return false
+		}
+	}
+	m.slots[free].kv, m.slots[free].used = kv, true
+	m.slots[home].hop |= 1 << d
+	m.size++
+	return true
+}
+
+// resize doubles the size of the map if it is more than half full, and
+// otherwise rehashes it in place with a new seed: keys that cannot be placed in
+// the neighborhood of their home slot in a map that empty are much more likely
+// to be caused by the hash function than by the fill ratio. This keeps the
+// capacity within twice the one needed for the number of keys.
+//
+func (m *HopscotchMap[V]) resize() {
+	slots, l, threshold := m.slots, len(m.slots), m.threshold
+	if m.size > threshold>>1 {
+		l, threshold = l<<1, threshold<<1
+		if l < 0 {
+			panic("map size overflows addressable space")
+		}
+	} else {
+		// the same seed and size would give the same layout
+		m.seed = uint(rand.Uint64())
+	}
+	for !m.rehash(slots, l, threshold) {
+		m.seed = uint(rand.Uint64())
+	}
+}
+
+// rehash moves all keys in slots to a new backing array of length l. It
+// returns false if some key could not be placed.
+//
+func (m *HopscotchMap[V]) rehash(slots []hopSlot[V], l, threshold int) bool {
+	m.slots = make([]hopSlot[V], l)
+	m.size = 0
+	m.threshold = threshold
+	for i := range slots {
+		if slots[i].used && !m.insert(slots[i].kv) {
+			return false
+		}
+	}
+	return true
+}
+
+// Get returns the value associated with the given key and true, or the zero
+// value for V and false if the key is not set.
+//
+func (m *HopscotchMap[V]) Get(key int) (v V, ok bool) {
+	if i := m.find(key); i >= 0 {
+		return m.slots[i].kv.Value, true
+	}
+	return v, false
+}
+
+// Has returns true if the given key is set.
+//
+func (m *HopscotchMap[V]) Has(key int) bool {
+	return m.find(key) >= 0
+}
+
+// Delete deletes the given key and returns true if it was set.
+//
+func (m *HopscotchMap[V]) Delete(key int) bool {
+	i := m.find(key)
+	if i < 0 {
+		return false
+	}
+	home := m.home(key)
+	m.slots[home].hop &^= 1 << ((i - home) & (len(m.slots) - 1))
+	m.slots[i].kv, m.slots[i].used = KeyValue[V]{}, false
+	m.size--
+	return true
+}
+
+// Clear removes all keys from the map. It keeps the allocated storage.
+//
+func (m *HopscotchMap[V]) Clear() {
+	clear(m.slots)
+	m.size = 0
+}
+
+// Len returns the number if keys set in the map.
+//
+func (m *HopscotchMap[V]) Len() int {
+	return m.size
+}
+
+// Cap returns the capacity of the map, that is the number of slots in its
+// backing array.
+//
+func (m *HopscotchMap[V]) Cap() int {
+	return len(m.slots)
+}
+
+// Range calls f sequentially for each key and value present in the map. If f
+// returns false, Range stops the iteration. f must not modify the map.
+//
+func (m *HopscotchMap[V]) Range(f func(key int, value V) bool) {
+	for i := range m.slots {
+		if s := &m.slots[i]; s.used && !f(s.kv.Key, s.kv.Value) {
+			return
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package intmap_test
+
+import (
+	"math/bits"
+	"testing"
+
+	"github.com/db47h/intmap"
+)
+
+func TestHopscotchMap_CapHighBits(t *testing.T) {
+	var m intmap.HopscotchMap[int]
+	for j := 1; j <= 17; j++ {
+		m.Set(j<<(bits.UintSize-12), j)
+	}
+	// 32 slots hold 17 keys at the default fill ratio
+	if c := m.Cap(); c > 64 {
+		t.Errorf("Cap() = %d for %d keys", c, m.Len())
+	}
+	for j := 1; j <= 17; j++ {
+		if v, _ := m.Get(j << (bits.UintSize - 12)); v != j {
+			t.Errorf("bad value for key %d: %d", j, v)
+		}
+	}
+}
+
+func TestHopscotchMap_SameHome(t *testing.T) {
+	m := intmap.NewHopscotch[int](1024, 0.9)
+	// more keys with the same home slot than fit in a neighborhood
+	var keys []int
+	home := intmap.HopscotchHome(m, 1)
+	for k := 1; len(keys) < 17; k++ {
+		if intmap.HopscotchHome(m, k) == home {
+			keys = append(keys, k)
+		}
+	}
+	for _, k := range keys {
+		m.Set(k, k)
+	}
+	for _, k := range keys {
+		if v, ok := m.Get(k); !ok || v != k {
+			t.Errorf("bad value for key %d: %d, %v", k, v, ok)
+		}
+	}
+	if m.Cap() != 1024 {
+		t.Errorf("Cap() = %d, expected 1024", m.Cap())
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+import "iter"
+
+// All returns an iterator over the map's key/value pairs.
+//
+//	for k, v := range m.All() {
+//		fmt.Printf("m[%v] = %v\n", k, v)
+//	}
+//
+// The same restrictions as for Range apply to the loop body.
+//
+func (m *Map[V]) All() iter.Seq2[int, V] {
+	return m.Range
+}
+
+// KeysSeq returns an iterator over the map keys, in the same order as Keys.
+//
+func (m *Map[V]) KeysSeq() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		m.Range(func(k int, _ V) bool {
+			return yield(k)
+		})
+	}
+}
+
+// ValuesSeq returns an iterator over the map values, in the same order as
+// Keys.
+//
+func (m *Map[V]) ValuesSeq() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		m.Range(func(_ int, v V) bool {
+			return yield(v)
+		})
+	}
+}
+
+// Backward returns an iterator over the map's key/value pairs that walks the
+// backing array in reverse order, starting right before a free slot and
+// wrapping around, then yields the free key last. This is the reverse of All
+// rotated to that free slot, not the exact reverse of All.
+//
+// Besides changing the value of any existing key, the loop body may safely
+// delete the current key.
+//
+func (m *Map[V]) Backward() iter.Seq2[int, V] {
+	return func(yield func(int, V) bool) {
+		es := m.es
+		if l := len(es); l > 0 {
+			// Start right before a free slot: when deleting the current key,
+			// keys are shifted back within a cluster, and only keys already
+			// visited will move.
+			end := 0
+			for es[end].Key != m.free {
+				end++
+			}
+			mod := l - 1
+			for n, i := 0, end; n < l; n++ {
+				i = (i - 1) & mod
+				if k := es[i].Key; k != m.free && !yield(k, es[i].Value) {
+					return
+				}
+			}
+		}
+		if m.hasFreeKey {
+			yield(m.free, m.freeKeyValue)
+		}
+	}
+}
+
+// RangeErr calls fn sequentially for each key and value present in the map.
+// If fn returns a non-nil error, RangeErr stops the iteration and returns it.
+//
+// The same restrictions as for Range apply to fn.
+//
+func (m *Map[V]) RangeErr(fn func(key int, value V) error) error {
+	var err error
+	m.Range(func(k int, v V) bool {
+		err = fn(k, v)
+		return err == nil
+	})
+	return err
+}
+
+// Collect returns a new Map holding all key-value pairs from seq. If a key
+// appears more than once, the last value wins.
+//
+func Collect[V any](seq iter.Seq2[int, V]) *Map[V] {
+	var m Map[V]
+	for k, v := range seq {
+		m.Set(k, v)
+	}
+	return &m
+}
@@ -0,0 +1,104 @@
+package intmap_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/db47h/intmap"
+)
+
+func TestMap_All(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 100; k++ {
+		m.Set(k, k)
+	}
+	sum, n := 0, 0
+	for k, v := range m.All() {
+		if k != v {
+			t.Errorf("bad value for key %d: %d", k, v)
+		}
+		sum += v
+		n++
+		if n == 100 {
+			break
+		}
+	}
+	if sum != 4950 {
+		t.Errorf("bad sum: expected 4950, got %d", sum)
+	}
+}
+
+func TestMap_KeysSeq(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 100; k++ {
+		m.Set(k, -k)
+	}
+	ks, vs := slices.Collect(m.KeysSeq()), slices.Collect(m.ValuesSeq())
+	if !slices.Equal(ks, m.Keys()) || !slices.Equal(vs, m.Values()) {
+		t.Error("sequences do not match slice accessors")
+	}
+}
+
+func TestMap_Backward(t *testing.T) {
+	m := intmap.New[int](128, 0.99)
+	for k := 0; k < 120; k++ {
+		m.Set(k*128, k)
+	}
+	m.Set(0, 0)
+	seen := make(map[int]int)
+	for k, v := range m.Backward() {
+		seen[k]++
+		if v%2 == 0 {
+			m.Delete(k)
+		}
+	}
+	if len(seen) != 120 {
+		t.Errorf("bad number of keys visited: expected 120, got %d", len(seen))
+	}
+	for k, n := range seen {
+		if n != 1 {
+			t.Errorf("key %d visited %d times", k, n)
+		}
+	}
+	if m.Len() != 60 {
+		t.Errorf("bad size: expected 60, got %d", m.Len())
+	}
+}
+
+func TestMap_RangeErr(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 100; k++ {
+		m.Set(k, k)
+	}
+	errStop := errors.New("stop")
+	n := 0
+	err := m.RangeErr(func(k, v int) error {
+		if n++; n == 10 {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop || n != 10 {
+		t.Errorf("RangeErr() = %v after %d calls", err, n)
+	}
+	if err = m.RangeErr(func(int, int) error { return nil }); err != nil {
+		t.Errorf("RangeErr() = %v", err)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 100; k++ {
+		m.Set(k, -k)
+	}
+	c := intmap.Collect(m.Backward())
+	if c.Len() != 100 {
+		t.Fatalf("bad size: expected 100, got %d", c.Len())
+	}
+	for k := 0; k < 100; k++ {
+		if v, _ := c.Get(k); v != -k {
+			t.Errorf("bad value for key %d: %d", k, v)
+		}
+	}
+}
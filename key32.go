@@ -0,0 +1,27 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+// Map32 is a variant of Map with 32 bits keys. On 64 bits platforms, this
+// halves the memory used by keys and improves cache density.
+//
+// Map32 implements the core subset of the Map API, provided by its embedded
+// MapOf. The zero value is an empty map ready to use. A Map32 must not be
+// copied after first use.
+//
+type Map32[V any] struct {
+	MapOf[int32, V]
+}
+
+// New32 returns a new Map32 initialized with the given starting capacity and
+// fill ratio.
+//
+// See Map.Init for more details about the capacity and fillratio parameters.
+//
+func New32[V any](capacity int, fillratio float32) *Map32[V] {
+	var m Map32[V]
+	m.Init(capacity, fillratio)
+	return &m
+}
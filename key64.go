@@ -0,0 +1,30 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+// Map64 is a variant of Map with 64 bits keys on all platforms. Unlike Map,
+// whose keys are only 32 bits wide on 32 bits platforms, a Map64 hashes the
+// full 64 bits of its keys on 386, arm and amd64 alike, so that 64 bits IDs
+// never collide through truncation. Each map picks a random hash seed, so the
+// iteration order is unspecified, as with Map.
+//
+// Map64 implements the core subset of the Map API, provided by its embedded
+// MapOf. The zero value is an empty map ready to use. A Map64 must not be
+// copied after first use.
+//
+type Map64[V any] struct {
+	MapOf[int64, V]
+}
+
+// New64 returns a new Map64 initialized with the given starting capacity and
+// fill ratio.
+//
+// See Map.Init for more details about the capacity and fillratio parameters.
+//
+func New64[V any](capacity int, fillratio float32) *Map64[V] {
+	var m Map64[V]
+	m.Init(capacity, fillratio)
+	return &m
+}
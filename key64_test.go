@@ -0,0 +1,20 @@
+package intmap_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/db47h/intmap"
+)
+
+func TestMap64_Extremes(t *testing.T) {
+	m := intmap.New64[int](16, 0.75)
+	m.Set(math.MinInt64, -1)
+	m.Set(math.MaxInt64, 1)
+	if v, ok := m.Get(math.MinInt64); !ok || v != -1 {
+		t.Errorf("bad value for key %d: %d, %v", int64(math.MinInt64), v, ok)
+	}
+	if v, ok := m.Get(math.MaxInt64); !ok || v != 1 {
+		t.Errorf("bad value for key %d: %d, %v", int64(math.MaxInt64), v, ok)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+// Integer is the set of key types supported by MapOf.
+//
+type Integer interface {
+	~int | ~int32 | ~int64 | ~uint | ~uint32 | ~uint64 | ~uintptr
+}
+
+// KeyValueOf wraps a key-value pair of a MapOf or PtrMap.
+//
+type KeyValueOf[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// MapOf is a variant of Map with a generic key type. This allows keying maps
+// by unsigned IDs or by typed integers, like enums, without converting them to
+// int and losing type safety.
+//
+// Keys of any width are hashed with the same seeded 64 bits hash, regardless of
+// the size of int on the target architecture. Keys of any type are supported
+// over their full range: a MapOf[uint64, V] accepts any uint64 key, like IDs
+// with the high bit set or math.MaxUint64, and only the zero key is stored
+// separately.
+//
+// MapOf implements the core subset of the Map API. The zero value is an empty
+// map ready to use. A MapOf must not be copied after first use.
+//
+type MapOf[K Integer, V any] struct {
+	table[K, V]
+}
+
+// NewOf returns a new MapOf initialized with the given starting capacity and
+// fill ratio.
+//
+// See Map.Init for more details about the capacity and fillratio parameters.
+//
+func NewOf[K Integer, V any](capacity int, fillratio float32) *MapOf[K, V] {
+	var m MapOf[K, V]
+	m.Init(capacity, fillratio)
+	return &m
+}
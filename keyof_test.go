@@ -0,0 +1,56 @@
+package intmap_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/db47h/intmap"
+)
+
+type colorID int32
+
+func TestMapOf_NamedType(t *testing.T) {
+	var e intmap.MapOf[colorID, string]
+	e.Set(-1, "none")
+	e.Set(0, "black")
+	e.Set(42, "red")
+	e.Range(func(k colorID, v string) bool {
+		if w, _ := e.Get(k); w != v {
+			t.Errorf("bad value for key %d: %q", k, w)
+		}
+		return true
+	})
+	if v, _ := e.Get(0); v != "black" || e.Len() != 3 {
+		t.Errorf("bad value for key 0: %q", v)
+	}
+}
+
+func TestMapOf_Uint64FullRange(t *testing.T) {
+	var m intmap.MapOf[uint64, uint64]
+	keys := []uint64{0, 1, math.MaxUint64, math.MaxUint64 - 1, 1 << 63, 1<<63 - 1, 1<<63 + 1}
+	// snowflake-like IDs: timestamp in the high bits, sequence in the low bits
+	for i := uint64(0); i < 1000; i++ {
+		keys = append(keys, 1<<63|(i/10+1)<<22|i%10)
+	}
+	for _, k := range keys {
+		m.Set(k, ^k)
+	}
+	if m.Len() != len(keys) {
+		t.Fatalf("Len() = %d, expected %d", m.Len(), len(keys))
+	}
+	for _, k := range keys {
+		if v, ok := m.Get(k); !ok || v != ^k {
+			t.Errorf("bad value for key %#x: %#x, %v", k, v, ok)
+		}
+	}
+	for _, k := range keys[:7] {
+		if !m.Delete(k) || m.Has(k) {
+			t.Errorf("failed to delete key %#x", k)
+		}
+	}
+	for _, k := range keys[7:] {
+		if v, ok := m.Get(k); !ok || v != ^k {
+			t.Errorf("bad value for key %#x after deletions: %#x, %v", k, v, ok)
+		}
+	}
+}
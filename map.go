@@ -10,15 +10,18 @@ Set and Get operations are consistently faster than the builtin
 map[int]interface{}: Get takes from 25 to 50% less time, depending on CPU
 architecture and map size. On some CPUs like an AMD FX, there is even an actual
 drop off point in map size (~16384) where the builtin map gets slightly faster.
+Since Go 1.24, the builtin map is based on Swiss tables and the gap is much
+narrower.
 
-Benchmark sample (*Builtin are performed using a regular map[int]interface{}):
+Benchmark sample with Go 1.27 on amd64 (*Builtin are performed using a regular
+map[int]interface{}):
 
-    BenchmarkIntMapSet-6            28105802                37.53 ns/op
-    BenchmarkBuiltinMapSet-6        21551445                53.44 ns/op
-    BenchmarkIntMapGet-6            35170137                33.10 ns/op
-    BenchmarkBuiltinMapGet-6        26973751                44.79 ns/op
-    BenchmarkIntMapDelete-6         33600421                32.16 ns/op
-    BenchmarkBuiltinMapDelete-6     38036679                28.68 ns/op
+    BenchmarkIntMapSet              90632678                34.89 ns/op
+    BenchmarkBuiltinMapSet         100000000                33.76 ns/op
+    BenchmarkIntMapGet              94337976                33.38 ns/op
+    BenchmarkBuiltinMapGet         100000000                35.54 ns/op
+    BenchmarkIntMapDelete           98522013                33.23 ns/op
+    BenchmarkBuiltinMapDelete      133711029                27.19 ns/op
 
 The delete test is wrong since we end up deleting millions of non-existent keys,
 which is not a typical use case. Regardless, deletes are slower than with the
@@ -32,9 +35,43 @@ http://java-performance.info/implementing-world-fastest-java-int-to-int-hash-map
 
 The stored values can be of any type.
 
+A Map is unseeded by default, which gives the fastest hash function. Maps
+holding keys from an untrusted source should be given a random seed with
+SetSeed, which is mixed non-linearly into the hash of keys so that these keys
+cannot be crafted to collide, or reseed automatically with SetProbeLimit. The
+other hashed map types pick a random seed per map. See Map.SetSeed.
+
+
+Memory usage
+
+When a Map grows, its keys are rehashed from the old backing array into a new
+one twice as large, so that peak memory usage during growth is about 1.5 times
+the size of the new backing array. If the final size of a Map is known, or can
+be approximated, calling Grow, or Init with an appropriate capacity, avoids
+intermediate rehashes altogether. Otherwise, SegmentedMap splits its keys over
+segments of bounded size that are grown or split one at a time, so that the
+extra memory needed for growth does not depend on the size of the map.
+
+When V contains no pointers, neither does the backing array of a Map. The Go
+runtime allocates such arrays in noscan memory, so that the garbage collector
+never scans their contents, regardless of their size. When V does contain
+pointers, the whole backing array, keys included, must be scanned; SplitMap
+keeps keys in a separate pointer-free array and only the value array is scanned.
+
 */
 package intmap
 
+import (
+	"errors"
+	"hash/maphash"
+	"math/bits"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strconv"
+	"unsafe"
+)
+
 // KeyValue wraps a key-value pair.
 //
 type KeyValue[V any] struct {
@@ -51,7 +88,9 @@ const (
 // order to improve data locality.
 //
 // The primary use case for this implementation is that of small maps
-// (regardless of the size of the key set) with almost no deletions.
+// (regardless of the size of the key set) with almost no deletions. Maps with
+// many deletions and insertions, like session tables, should use tombstones
+// with periodic compaction instead; see SetTombstones and SetCompactRatio.
 //
 // A Map can be used directly: the start capacity will be set to 8 entries and
 // the fill ratio 87.5%. If the rough map size is known in advance, it is
@@ -59,20 +98,273 @@ const (
 // especially when initializing a large number of maps.
 //
 // When the size of a Map grows over the fill ratio, its capacity is doubled.
-// Maps are never shrunk when deleting keys.
+// Maps are never shrunk when deleting keys, unless Shrink is called.
+//
+// Small maps, with a capacity of up to 8 entries, are stored in an array
+// embedded in the Map and do not need any heap allocation. As a consequence, a
+// Map must not be copied after first use.
 //
 type Map[V any] struct {
+	_ noCopy // not last, where it would be padded
+
 	es           []KeyValue[V]
 	size         int
 	threshold    int
+	free         int // key marking free slots
 	hasFreeKey   bool
-	freeKeyValue V
+	freeKeyValue V    // value of the free key, which is stored separately
+	boundsValid  bool // minKey and maxKey are up to date
+	minKey       int
+	maxKey       int
+	popIdx       int // where Pop starts looking for a key
+	mods         int // structural modification counter
+
+	deterministic bool  // use archHash
+	fibonacci     bool  // use the high bits of the hash as slot index
+	triangular    bool  // probe with triangular numbers instead of linearly
+	tombstones    bool  // deleted keys leave tombstones with linear probing
+	sortBuf       []int // key buffer for SortedIterator
+	seed          uint
+	seedMode      uint8
+
+	lowWater float32 // auto-shrink load factor
+	shrinkAt int     // size under which to auto-shrink, 0 if disabled
+	maxLen   int     // maximum number of keys, 0 if unbounded
+
+	probeLimit int       // probe length above which to reseed, 0 if disabled
+	onReseed   func(int) // called after an automatic reseed
+	noReseed   bool      // disable reseeding while rehashing or after a reseed
+	reseedLen  int       // capacity at the last automatic reseed
+
+	tombs        int      // number of tombstones
+	tombBits     []uint64 // tombstone bitmap, one bit per slot
+	compactRatio float32  // tombstone to capacity ratio that triggers compaction
+
+	alloc   func(n int) []KeyValue[V]
+	release func(es []KeyValue[V])
+	userBuf *KeyValue[V] // first entry of the buffer provided to InitBuffer
+
+	inline [inlineCap]KeyValue[V] // storage for small maps
+}
+
+// noCopy may be embedded in structs that must not be copied after first use,
+// so that copies are reported by the copylocks check of go vet.
+//
+type noCopy struct{}
+
+func (*noCopy) Lock()   {}
+func (*noCopy) Unlock() {}
+
+// inlineCap is the capacity of the storage embedded in a Map. Maps with a
+// capacity less than or equal to inlineCap do not allocate.
+//
+const inlineCap = 8
+
+// seed modes
+const (
+	seedNone   = iota // unseeded, the default
+	seedRandom        // random seed picked by an automatic reseed
+	seedUser          // seed set with SetSeed
+)
+
+// ErrFull is returned by TrySet when inserting a new key in a map that already
+// holds the maximum number of keys set by SetMaxLen.
+//
+var ErrFull = errors.New("map is full")
+
+// hash returns the hash of key. A non-zero seed is mixed in non-linearly: XORing
+// it with the key before a fixed multiplication would leave the differences
+// between keys, and therefore their collisions, unchanged.
+//
+func (m *Map[V]) hash(key int) int {
+	if m.seed == 0 {
+		if m.deterministic {
+			return archHash(key)
+		}
+		return hash(key)
+	}
+	return int(mix64(uint64(int64(key)) ^ uint64(m.seed)))
+}
+
+// fibMul is 2^64 divided by the golden ratio.
+//
+const fibMul = 0x9E3779B97F4A7C15
+
+// slot returns the home slot of key in a backing array of length mod+1.
+//
+func (m *Map[V]) slot(key, mod int) int {
+	if m.seed == 0 && !m.fibonacci && !m.deterministic {
+		// default configuration, kept small enough to be inlined
+		return hash(key) & mod
+	}
+	return m.customSlot(key, mod)
+}
+
+// customSlot returns the home slot of key with a seeded, Fibonacci or
+// deterministic hash function.
+//
+func (m *Map[V]) customSlot(key, mod int) int {
+	if m.fibonacci {
+		h := uint64(int64(key)) * (fibMul ^ uint64(m.seed)<<1)
+		return int(h >> bits.LeadingZeros64(uint64(mod)))
+	}
+	return m.hash(key) & mod
+}
+
+// SetFibonacciHashing enables or disables Fibonacci hashing and rehashes the
+// map if it is not empty.
+//
+// By default, the slot of a key is given by the low bits of a mixed hash of the
+// key. With Fibonacci hashing, the key is multiplied by an odd constant and the
+// high bits of the product are used instead. The constant is 2^64 divided by
+// the golden ratio, randomized by the hash seed. This spreads sequential or
+// evenly spaced keys more uniformly over the backing array and shortens probe
+// sequences for such key sets, at the cost of a slightly more expensive hash on
+// 32 bits platforms.
+//
+func (m *Map[V]) SetFibonacciHashing(on bool) {
+	if m.fibonacci == on {
+		return
+	}
+	m.fibonacci = on
+	if m.size > 0 {
+		m.resize(len(m.es), m.threshold)
+	}
+}
+
+// SetMaphashSeed sets the seed of the hash function from a hash/maphash seed
+// and rehashes the map if it is not empty. Maps given the same maphash seed use
+// the same hash function, so that keys can be consistently sharded or ordered
+// across maps, following the seeding policy used for hash/maphash in the rest
+// of a program.
+//
+// Keys are not hashed with hash/maphash itself, which would be much slower;
+// only the seed is derived from it.
+//
+func (m *Map[V]) SetMaphashSeed(seed maphash.Seed) {
+	m.SetSeed(maphash.String(seed, "intmap"))
+}
+
+// SetSeed sets the seed of the hash function and rehashes the map if it is not
+// empty. On 32 bits platforms, only the low 32 bits of seed are used.
+//
+// By default, maps are unseeded: keys are hashed with a slightly faster
+// function that does not mix in any seed, and keys from an untrusted source
+// can be chosen to collide and degrade performance. Giving such maps a random
+// seed, for example from math/rand/v2.Uint64, prevents this. A zero seed
+// restores the unseeded hash function.
+//
+func (m *Map[V]) SetSeed(seed uint64) {
+	m.seed = uint(seed)
+	m.seedMode = seedUser
+	if m.size > 0 {
+		m.resize(len(m.es), m.threshold)
+	}
 }
 
+// archHash is a hash function that yields the same low 31 bits on all
+// architectures for any key that fits in 32 bits.
+//
+func archHash(v int) int {
+	x := uint64(int64(v)) * 0x9E3779B97F4A7C15
+	return int(x ^ (x >> 32))
+}
+
+// linearScanCap is the capacity up to which lookups are performed with a linear
+// scan of the backing array instead of hashing. Keys are still placed at their
+// hashed position when inserted.
+//
+const linearScanCap = 16
+
 func nextIdx(idx int) int {
 	return idx + 1
 }
 
+// next returns the slot to look at after idx for the given step of a probe
+// sequence, starting at 1.
+//
+func (m *Map[V]) next(idx, step, mod int) int {
+	if m.triangular {
+		return (idx + step) & mod
+	}
+	return nextIdx(idx) & mod
+}
+
+// SetTriangularProbing enables or disables triangular probing and rehashes the
+// map if it is not empty.
+//
+// With linear probing, the default, colliding keys are stored in the slots
+// right after their home slot, and keys with neighboring home slots tend to
+// form long clusters at high fill ratios. With triangular probing, the i-th
+// slot looked at for a key is at home + i*(i+1)/2, which breaks up these
+// clusters.
+//
+// Since keys cannot be shifted back along non-linear probe sequences, deleted
+// keys then leave tombstones behind: they are skipped by lookups, reused by
+// insertions, and cleared when the map is rehashed.
+//
+func (m *Map[V]) SetTriangularProbing(on bool) {
+	if m.triangular == on {
+		return
+	}
+	m.triangular = on
+	if m.size > 0 || m.tombs > 0 {
+		m.resize(len(m.es), m.threshold)
+	}
+}
+
+// SetTombstones selects the deletion strategy used with linear probing and
+// rehashes the map if it has tombstones and on is false.
+//
+// By default, deleting a key shifts back the following keys of its cluster
+// that would otherwise become unreachable. When on is true, deleted keys leave
+// tombstones behind instead, which are skipped by lookups and reused by
+// insertions. For bursts of deletions followed by insertions of the same keys,
+// this does far fewer memory writes. A tombstone directly followed by a free
+// slot is turned into a free slot, along with the tombstones right before it,
+// and all tombstones are cleared whenever the map is rehashed.
+//
+// Tombstones count towards the fill ratio: when keys and tombstones together
+// exceed it, the map is rehashed, into a backing array of the same size if most
+// of the used slots are tombstones.
+//
+// Triangular probing always uses tombstones, regardless of this setting.
+//
+func (m *Map[V]) SetTombstones(on bool) {
+	if m.tombstones == on {
+		return
+	}
+	m.tombstones = on
+	if !m.useTombs() && m.tombs > 0 {
+		m.resize(len(m.es), m.threshold)
+	}
+}
+
+// useTombs reports whether deleted keys leave tombstones.
+//
+func (m *Map[V]) useTombs() bool {
+	return m.triangular || m.tombstones
+}
+
+// isTomb reports whether the free slot at idx is a tombstone.
+//
+func (m *Map[V]) isTomb(idx int) bool {
+	return m.tombs > 0 && m.tombBits[idx/64]&(1<<(idx%64)) != 0
+}
+
+// clearTombs removes all tombstones.
+//
+func (m *Map[V]) clearTombs() {
+	m.tombs = 0
+	m.tombBits = nil
+}
+
+// full reports whether the map must be rehashed before inserting a new key.
+//
+func (m *Map[V]) full() bool {
+	return m.size+m.tombs >= m.threshold
+}
+
 // New returns a new Map initialized with the given starting capacity and fill
 // ratio.
 //
@@ -117,231 +409,1384 @@ func (m *Map[V]) Init(capacity int, fillratio float32) {
 	if capacity < 2 {
 		capacity = 2
 	}
-	threshold := int(float32(capacity) * fillratio)
-	if threshold <= 0 {
-		threshold = 1
-	} else if threshold >= capacity {
-		threshold = capacity - 1
-	}
-	m.es = make([]KeyValue[V], capacity)
+	threshold := thresholdFor(capacity, fillratio)
+	es := m.es
+	m.es = nil
+	m.freeEntries(es)
+	m.es = m.makeEntries(capacity)
 	m.size = 0
 	m.threshold = threshold
 	m.hasFreeKey = false
+	m.boundsValid = false
+	m.clearTombs()
+	m.mods++
+	m.updateShrinkAt()
 }
 
-// Set sets or resets the value for the given key.
+// InitBuffer initializes the Map with the given fill ratio, using buf as its
+// backing array. Any data in buf is cleared. See Init for more details about
+// the fill ratio.
 //
-func (m *Map[V]) Set(key int, value V) {
-	if key == freeKey {
-		m.hasFreeKey = true
-		m.freeKeyValue = value
+// It returns an error if the length of buf is not a power of two greater than
+// or equal to 2.
+//
+// This allows using statically allocated storage. Once the map outgrows buf, a
+// new backing array is allocated as usual and buf is no longer used.
+//
+func (m *Map[V]) InitBuffer(buf []KeyValue[V], fillratio float32) error {
+	l := len(buf)
+	if l < 2 || l&(l-1) != 0 {
+		return errors.New("buffer length is not a power of two >= 2")
+	}
+	m.clearEntries(buf)
+	m.freeEntries(m.es)
+	m.es = buf
+	m.userBuf = &buf[0]
+	m.size = 0
+	m.threshold = thresholdFor(l, fillratio)
+	m.hasFreeKey = false
+	m.boundsValid = false
+	m.clearTombs()
+	m.mods++
+	m.updateShrinkAt()
+	return nil
+}
+
+// SetDeterministicOrder enables or disables deterministic iteration order.
+//
+// By default, the hash function depends on the size of int on the target
+// architecture, and so does the iteration order. When enabled, a hash function
+// that gives the same results on all architectures for keys in the 32 bits
+// range is used instead, at a slight performance cost on 32 bits platforms.
+// Iteration order then only depends on the sequence of operations applied to
+// the map since its last call to Init. Unless set explicitly with SetSeed, the
+// hash seed is then 0.
+//
+// If the map is not empty, it is rehashed by inserting its keys in ascending
+// order.
+//
+func (m *Map[V]) SetDeterministicOrder(on bool) {
+	if m.deterministic == on {
 		return
 	}
-	l := len(m.es)
-	if m.size >= m.threshold {
-		// over fillratio, rehash
-		if l == 0 {
-			l = 8
-			m.es = make([]KeyValue[V], l)
-			m.threshold = int(defaultFillRatio * float32(l)) // use a default fillratio of 87.5%
-		} else {
-			l *= 2
-			m.rehash()
-		}
+	m.deterministic = on
+	if on && m.seedMode == seedRandom {
+		m.seed = 0
+		m.seedMode = seedNone
 	}
-
-	mod := l - 1
-	idx := hash(key) & mod
-	for {
-		switch m.es[idx].Key {
-		case freeKey:
-			m.size++
-			fallthrough
-		case key:
-			m.es[idx] = KeyValue[V]{key, value}
-			return
+	if m.size == 0 {
+		return
+	}
+	es := make([]KeyValue[V], 0, m.size)
+	for i := range m.es {
+		if m.es[i].Key != m.free {
+			es = append(es, m.es[i])
 		}
-		idx = nextIdx(idx) & mod
 	}
+	sort.Slice(es, func(i, j int) bool { return es[i].Key < es[j].Key })
+	m.clearEntries(m.es)
+	m.size = 0
+	m.clearTombs()
+	m.shrinkAt = 0
+	m.mods++
+	m.reinsert(es, false)
+	m.updateShrinkAt()
 }
 
-func (m *Map[V]) rehash() {
-	es := m.es
-	l := len(es) << 1
-	if l < 0 {
-		panic("map size overflows addressable space")
+// SetFreeKey sets the key that marks free slots in the backing array, which is
+// 0 by default. Since that key cannot be stored in the array itself, it is
+// kept separately and goes through a slower path in all operations. Maps where
+// 0 is a common key should use a key that is known never to be set instead,
+// like math.MinInt for signed IDs.
+//
+// If the map is not empty, it is rehashed. The free key can still be set like
+// any other key.
+//
+func (m *Map[V]) SetFreeKey(key int) {
+	if key == m.free {
+		return
 	}
-	m.es = make([]KeyValue[V], l)
-	m.size = 0
-	m.threshold <<= 1
-	for i := range es {
-		if es[i].Key != freeKey {
-			m.Set(es[i].Key, es[i].Value)
-		}
+	var kvs []KeyValue[V]
+	if m.Len() > 0 {
+		kvs = m.Entries()
 	}
+	var zv V
+	m.free = key
+	m.hasFreeKey = false
+	m.freeKeyValue = zv
+	m.clearEntries(m.es)
+	m.size = 0
+	m.clearTombs()
+	m.shrinkAt = 0
+	m.mods++
+	m.reinsert(kvs, false)
+	m.updateShrinkAt()
 }
 
-// Get returns the value associated with the given key and ok set to true if the key exists.
-// If the keys does not exist, it returns the zero value for the Value type and false.
+// FreeKey returns the key that marks free slots in the backing array.
 //
-func (m *Map[V]) Get(key int) (v V, ok bool) {
-	if key == freeKey {
-		if m.hasFreeKey {
-			return m.freeKeyValue, true
-		}
-		return v, false
-	}
-	mod := len(m.es) - 1
-	if mod < 0 {
-		return v, false
-	}
-	startIdx := hash(key) & mod
-	idx := startIdx
-	for {
-		t := &m.es[idx]
-		switch t.Key {
-		case freeKey:
-			return v, false
-		case key:
-			return t.Value, true
-		}
-		idx = nextIdx(idx) & mod
-		if idx == startIdx {
-			return v, false
-		}
-	}
+func (m *Map[V]) FreeKey() int {
+	return m.free
 }
 
-// Delete deletes the given key and returns true if the key was present in the map.
+// thresholdFor returns the rounded threshold for the given capacity and fill
+// ratio, as documented in Map.Init.
 //
-func (m *Map[V]) Delete(key int) bool {
-	if key == freeKey {
-		var zv V
-		rv := m.hasFreeKey
-		m.freeKeyValue = zv
-		m.hasFreeKey = false
-		return rv
+func thresholdFor(capacity int, fillratio float32) int {
+	threshold := int(float32(capacity) * fillratio)
+	if threshold <= 0 {
+		threshold = 1
+	} else if threshold >= capacity {
+		threshold = capacity - 1
 	}
-	mod := len(m.es) - 1
-	if mod < 0 {
-		return false
+	return threshold
+}
+
+// SetFillRatio changes the fill ratio of the map. The fill ratio is rounded as
+// described in Init. If the map holds more keys than allowed by the new fill
+// ratio, it is grown as needed.
+//
+// If the map is not initialized, SetFillRatio initializes it with a capacity of
+// 8.
+//
+func (m *Map[V]) SetFillRatio(fillratio float32) {
+	if len(m.es) == 0 {
+		hasFreeKey := m.hasFreeKey
+		m.Init(8, fillratio)
+		m.hasFreeKey = hasFreeKey
+		return
 	}
-	startIdx := hash(key) & mod
-	idx := startIdx
-	for {
-		switch m.es[idx].Key {
-		case freeKey:
-			return false
-		case key:
-			m.shiftKeys(idx)
-			m.size--
-			return true
-		}
-		idx = nextIdx(idx) & mod
-		if idx == startIdx {
-			return false
+	l := len(m.es)
+	threshold := thresholdFor(l, fillratio)
+	for threshold < m.size {
+		l <<= 1
+		if l < 0 {
+			panic("map size overflows addressable space")
 		}
+		threshold = thresholdFor(l, fillratio)
+	}
+	if l != len(m.es) {
+		m.resize(l, threshold)
+	} else {
+		m.threshold = threshold
+		m.updateShrinkAt()
 	}
 }
 
-func (m *Map[V]) shiftKeys(idx int) {
-	var k int
-	mod := len(m.es) - 1
-	for {
-		last := idx
-		idx = nextIdx(idx) & mod
-		for {
-			k = m.es[idx].Key
-			if k == freeKey {
-				m.es[last] = KeyValue[V]{Key: freeKey}
-				return
-			}
-			slot := hash(k) & mod
-			if last <= idx {
-				if last >= slot || slot > idx {
-					break
-				}
-			} else if last >= slot && slot > idx {
-				break
-			}
-			idx = nextIdx(idx) & mod
-		}
-		m.es[last] = KeyValue[V]{k, m.es[idx].Value}
-	}
+// Clear deletes all keys from the map. Unlike Init, it keeps the current
+// capacity and fill ratio and does not allocate.
+//
+func (m *Map[V]) Clear() {
+	var zv V
+	m.clearEntries(m.es)
+	m.size = 0
+	m.hasFreeKey = false
+	m.freeKeyValue = zv
+	m.boundsValid = false
+	m.clearTombs()
+	m.mods++
 }
 
-// Len returns the number if keys set in the map.
+// Reset deletes all keys from the map, restores the default settings for
+// deterministic order, automatic shrinking, maximum length and probe limit, and
+// restores the unseeded hash function. The capacity, fill ratio and internal
+// buffers are kept.
 //
-func (m *Map[V]) Len() int {
-	if m.hasFreeKey {
-		return m.size + 1
-	}
-	return m.size
+// Reset is meant for recycling maps, for example with a sync.Pool: a reset map
+// behaves like a new one, without allocating.
+//
+func (m *Map[V]) Reset() {
+	m.Clear()
+	m.deterministic = false
+	m.maxLen = 0
+	m.lowWater = 0
+	m.shrinkAt = 0
+	m.popIdx = 0
+	m.probeLimit = 0
+	m.onReseed = nil
+	m.reseedLen = 0
+	m.seed = 0
+	m.seedMode = seedNone
 }
 
-// Keys returns an unordered slice of the map keys.
+// Clone returns a copy of the map with the same capacity and fill ratio. The
+// values themselves are copied by assignment.
 //
-func (m *Map[V]) Keys() []int {
-	ks := make([]int, m.Len())
-	i := 0
-	if m.hasFreeKey {
-		ks[i] = freeKey
-		i++
+func (m *Map[V]) Clone() *Map[V] {
+	c := &Map[V]{
+		size:          m.size,
+		threshold:     m.threshold,
+		free:          m.free,
+		hasFreeKey:    m.hasFreeKey,
+		freeKeyValue:  m.freeKeyValue,
+		boundsValid:   m.boundsValid,
+		minKey:        m.minKey,
+		maxKey:        m.maxKey,
+		popIdx:        m.popIdx,
+		mods:          m.mods,
+		deterministic: m.deterministic,
+		fibonacci:     m.fibonacci,
+		triangular:    m.triangular,
+		tombstones:    m.tombstones,
+		seed:          m.seed,
+		seedMode:      m.seedMode,
+		lowWater:      m.lowWater,
+		shrinkAt:      m.shrinkAt,
+		maxLen:        m.maxLen,
+		probeLimit:    m.probeLimit,
+		onReseed:      m.onReseed,
+		reseedLen:     m.reseedLen,
+		tombs:         m.tombs,
+		tombBits:      slices.Clone(m.tombBits),
+		compactRatio:  m.compactRatio,
+		alloc:         m.alloc,
+		release:       m.release,
 	}
-	es := m.es
-	for e := range es {
-		if k := es[e].Key; k != freeKey {
-			ks[i] = k
-			i++
-		}
+	if m.es != nil {
+		c.es = c.makeEntries(len(m.es))
+		copy(c.es, m.es)
 	}
-	return ks
+	return c
 }
 
-// Iterator returns an iterator over the map's key/value pairs.
-//
-//	for i := m.Iterator(); i.HasNext(); {
-//		k, v := i.Next()
-//		fmt.Printf("m[%v] = %v\n", k, v)
-//	}
-//
-// While iterating over a map, deleting the value of the last key returned by
-// Next is supported as well of changing the value of any existing key.
-// Inserting new keys or deleting any other keys will break the iterator.
+// Grow grows the map capacity, if necessary, so that n additional keys can be
+// inserted without any rehash. The fill ratio is preserved. If the map is not
+// initialized, the default fill ratio of 87.5% is used.
 //
-func (m *Map[V]) Iterator() *Iterator[V] {
-	// find a sensible default for
-	return &Iterator[V]{m: m, lastKey: freeKey ^ -1, i: -1}
+func (m *Map[V]) Grow(n int) {
+	m.grow(n)
 }
 
-// Iterator represents an iterator over a map.
+// Shrink rehashes the map into the smallest backing array that can hold its
+// current keys while preserving the fill ratio.
 //
-type Iterator[V any] struct {
-	m       *Map[V]
-	lastKey int
-	i       int
+func (m *Map[V]) Shrink() {
+	l, threshold := len(m.es), m.threshold
+	for l > 2 && threshold>>1 >= m.size && threshold>>1 > 0 {
+		l >>= 1
+		threshold >>= 1
+	}
+	if l != len(m.es) {
+		m.resize(l, threshold)
+	}
 }
 
-// HasNext returns true if there are any keys left to read.
+// SetAutoShrink enables automatic shrinking of the map: after deletions have
+// brought its load factor under lowWater, the map capacity is halved on the
+// next call to DeleteFunc, DeleteMany or on the next insertion. A lowWater
+// value <= 0 disables automatic shrinking, which is the default.
 //
-func (i *Iterator[V]) HasNext() bool {
-	es := i.m.es
-	l := len(es)
-	if i.i < 0 {
-		// first call
-		if i.m.hasFreeKey && i.lastKey != freeKey {
-			return true
-		}
-		i.lastKey = freeKey
+// In order to prevent thrashing, lowWater is capped to a quarter of the fill
+// ratio, and maps are never shrunk below a capacity of 8.
+//
+func (m *Map[V]) SetAutoShrink(lowWater float32) {
+	m.lowWater = lowWater
+	m.updateShrinkAt()
+}
+
+const minShrinkCap = 8
+
+func (m *Map[V]) updateShrinkAt() {
+	l := len(m.es)
+	if m.lowWater <= 0 || l <= minShrinkCap {
+		m.shrinkAt = 0
+		return
+	}
+	at := int(m.lowWater * float32(l))
+	if max := m.threshold / 4; at > max {
+		at = max
+	}
+	m.shrinkAt = at
+}
+
+func (m *Map[V]) autoShrink() {
+	for m.size < m.shrinkAt {
+		m.resize(len(m.es)>>1, m.threshold>>1)
+	}
+}
+
+// SetCompactRatio enables automatic compaction of tombstones: once tombstones
+// make up more than ratio of the map capacity, they are cleared by rehashing
+// the map on the next insertion or on the next call to DeleteFunc or
+// DeleteMany. A ratio <= 0 disables automatic compaction, which is the default;
+// tombstones are then only cleared when keys and tombstones together exceed
+// the fill ratio.
+//
+// See SetTombstones.
+//
+func (m *Map[V]) SetCompactRatio(ratio float32) {
+	m.compactRatio = ratio
+}
+
+// CompactTombstones clears all tombstones by rehashing the map into a backing
+// array of the same size.
+//
+func (m *Map[V]) CompactTombstones() {
+	if m.tombs > 0 {
+		m.resize(len(m.es), m.threshold)
+	}
+}
+
+func (m *Map[V]) autoCompact() {
+	if m.compactDue() {
+		m.CompactTombstones()
+	}
+}
+
+func (m *Map[V]) compactDue() bool {
+	return m.tombs > 0 && m.compactRatio > 0 && float32(m.tombs) > m.compactRatio*float32(len(m.es))
+}
+
+// Set sets or resets the value for the given key.
+//
+func (m *Map[V]) Set(key int, value V) {
+	if key == m.free {
+		m.setFreeKey(value)
+		return
+	}
+	idx, _ := m.reserve(key)
+	m.es[idx].Value = value
+}
+
+// SetMaxLen sets the maximum number of keys the map can hold. A value of n <= 0
+// removes the limit, which is the default.
+//
+// Once the limit is reached, inserting new keys with TrySet fails with ErrFull,
+// while any other method that inserts keys, like Set, panics with ErrFull.
+// Setting the limit below the current length of the map does not delete any
+// keys.
+//
+func (m *Map[V]) SetMaxLen(n int) {
+	if n < 0 {
+		n = 0
+	}
+	m.maxLen = n
+}
+
+// TrySet sets or resets the value for the given key. It returns ErrFull if the
+// key does not exist and the map already holds the maximum number of keys set
+// by SetMaxLen.
+//
+func (m *Map[V]) TrySet(key int, value V) error {
+	if m.maxLen > 0 && m.Len() >= m.maxLen && !m.Has(key) {
+		return ErrFull
+	}
+	m.Set(key, value)
+	return nil
+}
+
+// Swap sets the value for the given key and returns the previous value, if
+// any. If the key did not exist, it returns the zero value for the Value type
+// and false.
+//
+func (m *Map[V]) Swap(key int, value V) (old V, existed bool) {
+	if key == m.free {
+		old, existed = m.freeKeyValue, m.hasFreeKey
+		m.setFreeKey(value)
+		return old, existed
+	}
+	idx, existed := m.reserve(key)
+	old, m.es[idx].Value = m.es[idx].Value, value
+	return old, existed
+}
+
+// CompareAndSwapFunc sets the value for the given key to new if the key exists
+// and its current value is equal to old according to eq. It reports whether the
+// value was swapped.
+//
+func (m *Map[V]) CompareAndSwapFunc(key int, old, new V, eq func(a, b V) bool) bool {
+	if key == m.free {
+		if !m.hasFreeKey || !eq(m.freeKeyValue, old) {
+			return false
+		}
+		m.freeKeyValue = new
+		return true
+	}
+	idx, ok := m.probe(key)
+	if !ok || !eq(m.es[idx].Value, old) {
+		return false
+	}
+	m.es[idx].Value = new
+	return true
+}
+
+// CompareAndSwap sets the value for the given key to new if the key exists and
+// its current value is equal to old. It reports whether the value was swapped.
+//
+func CompareAndSwap[V comparable](m *Map[V], key int, old, new V) bool {
+	return m.CompareAndSwapFunc(key, old, new, equal[V])
+}
+
+func equal[V comparable](a, b V) bool {
+	return a == b
+}
+
+// reserve returns the index of the slot for key, which must not be m.free.
+// If the key was not present, the map is grown as needed and the key is
+// inserted with a zero value.
+//
+func (m *Map[V]) reserve(key int) (idx int, existed bool) {
+	if len(m.es) == 0 {
+		m.es = m.makeEntries(8)
+		m.threshold = int(defaultFillRatio * 8) // use a default fillratio of 87.5%
+	}
+	mod := len(m.es) - 1
+	idx = m.slot(key, mod)
+	if !m.useTombs() {
+		// default configuration: plain linear probing
+		for probe := 0; ; probe++ {
+			switch m.es[idx].Key {
+			case m.free:
+				return m.reserveFree(key, idx, probe)
+			case key:
+				return idx, true
+			}
+			idx = nextIdx(idx) & mod
+		}
+	}
+	tomb := -1
+	for probe := 0; ; probe++ {
+		switch m.es[idx].Key {
+		case m.free:
+			if m.isTomb(idx) {
+				if tomb < 0 {
+					tomb = idx
+				}
+				break
+			}
+			if tomb >= 0 {
+				idx = tomb
+			}
+			return m.reserveFree(key, idx, probe)
+		case key:
+			return idx, true
+		}
+		idx = m.next(idx, probe+1, mod)
+	}
+}
+
+// reserveFree inserts key, which is not in the map, in the free slot at idx
+// found after the given number of probe steps, unless the map must first be
+// rehashed or reseeded.
+//
+func (m *Map[V]) reserveFree(key, idx, probe int) (int, bool) {
+	// The key is new: only now may the map be rehashed, so that updating
+	// existing keys never invalidates iterators.
+	m.checkLen()
+	if m.needsRehash() {
+		m.makeRoom()
+		return m.reserve(key)
+	}
+	if m.probeLimit > 0 && probe > m.probeLimit && !m.noReseed && len(m.es) != m.reseedLen {
+		m.reseed(probe)
+		m.noReseed = true
+		idx, existed := m.reserve(key)
+		m.noReseed = false
+		return idx, existed
+	}
+	m.insert(idx, key)
+	return idx, false
+}
+
+// needsRehash returns true if the map must be shrunk, compacted or grown
+// before inserting a new key.
+//
+func (m *Map[V]) needsRehash() bool {
+	return m.size < m.shrinkAt || m.compactDue() || m.full()
+}
+
+// makeRoom shrinks, compacts or grows the map as needed before inserting a new
+// key.
+//
+func (m *Map[V]) makeRoom() {
+	if m.size < m.shrinkAt {
+		m.autoShrink()
+	}
+	m.autoCompact()
+	if m.full() {
+		// over fillratio, rehash
+		if m.size < m.threshold/2 {
+			// mostly tombstones
+			m.resize(len(m.es), m.threshold)
+		} else {
+			m.rehash()
+		}
+	}
+}
+
+// SetProbeLimit enables automatic reseeding of the hash function: when a new
+// key would be inserted more than n slots away from its home slot, the map is
+// first rehashed with a new random seed, then f, if not nil, is called with the
+// probe length that triggered the reseed. A value of n <= 0 disables automatic
+// reseeding, which is the default.
+//
+// This protects long-running maps against key sets that happen to, or are
+// crafted to, degenerate into long probe sequences. The limit must be set well
+// above the probe lengths expected at the map's fill ratio, otherwise the map
+// will be rehashed over and over; 32 is a reasonable starting point.
+//
+// Once reseeded, a map is not reseeded again until its capacity changes, so
+// that key sets colliding under any seed, or a limit set too low, cost at most
+// one extra rehash per growth of the map instead of one per insertion.
+//
+// Reseeding overrides any seed set with SetSeed or SetMaphashSeed, and changes
+// the iteration order of maps in deterministic mode.
+//
+func (m *Map[V]) SetProbeLimit(n int, f func(probe int)) {
+	if n < 0 {
+		n = 0
+	}
+	m.probeLimit = n
+	m.onReseed = f
+	m.reseedLen = 0
+}
+
+// reseed rehashes the map with a new random seed.
+//
+func (m *Map[V]) reseed(probe int) {
+	m.seed = uint(rand.Uint64())
+	m.seedMode = seedRandom
+	m.resize(len(m.es), m.threshold)
+	m.reseedLen = len(m.es)
+	if m.onReseed != nil {
+		m.onReseed(probe)
+	}
+}
+
+func (m *Map[V]) rehash() {
+	l := len(m.es) << 1
+	if l < 0 {
+		panic("map size overflows addressable space")
+	}
+	m.resize(l, m.threshold<<1)
+}
+
+// grow makes room for at least n more keys without any further rehash.
+//
+func (m *Map[V]) grow(n int) {
+	l, threshold := len(m.es), m.threshold
+	if l == 0 {
+		l = 8
+		threshold = int(defaultFillRatio * float32(l))
+	}
+	for threshold < m.size+n {
+		l <<= 1
+		threshold <<= 1
+		if l < 0 {
+			panic("map size overflows addressable space")
+		}
+	}
+	// Rehashing also clears tombstones, which count against the threshold.
+	if l != len(m.es) || m.tombs > 0 && m.size+m.tombs+n > threshold {
+		m.resize(l, threshold)
+	}
+}
+
+// resize rehashes the map into a new backing slice of length l, which must be
+// a power of 2.
+//
+func (m *Map[V]) resize(l, threshold int) {
+	es := m.es
+	m.es = m.makeEntries(l)
+	m.size = 0
+	m.threshold = threshold
+	m.clearTombs()
+	m.shrinkAt = 0
+	m.mods++
+	m.reinsert(es, true)
+	m.freeEntries(es)
+	m.updateShrinkAt()
+}
+
+// reinsert sets all key-value pairs from es, skipping free slots if skipFree
+// is true. Since keys are only moved around, the limit set by SetMaxLen does
+// not apply and the map is never reseeded.
+//
+func (m *Map[V]) reinsert(es []KeyValue[V], skipFree bool) {
+	maxLen, noReseed := m.maxLen, m.noReseed
+	m.maxLen, m.noReseed = 0, true
+	for i := range es {
+		if !skipFree || es[i].Key != m.free {
+			m.Set(es[i].Key, es[i].Value)
+		}
+	}
+	m.maxLen, m.noReseed = maxLen, noReseed
+}
+
+// SetAllocator sets the functions used to allocate and release the map's
+// backing arrays. alloc must return a zeroed slice of length n. release, if not
+// nil, is called with backing arrays that are no longer used by the map. Passing
+// a nil alloc restores the default behavior of allocating backing arrays with
+// make and leaving them to the garbage collector.
+//
+// This allows keeping map storage in an arena or slab allocator, or recycling
+// discarded backing arrays with a TablePool. If the memory returned by alloc is
+// not managed by the Go runtime, the value type must not contain any pointers.
+//
+// The current backing array, if any, is not reallocated. Clones share the
+// allocator of the original map.
+//
+func (m *Map[V]) SetAllocator(alloc func(n int) []KeyValue[V], release func(es []KeyValue[V])) {
+	if alloc == nil {
+		release = nil
+	}
+	m.alloc = alloc
+	m.release = release
+}
+
+// makeEntries returns a new backing array of length n. The embedded storage is
+// used if it is large enough and not already in use by m.es.
+//
+func (m *Map[V]) makeEntries(n int) []KeyValue[V] {
+	var es []KeyValue[V]
+	switch {
+	case n <= inlineCap && !m.isInline(m.es):
+		es = m.inline[:n:n]
+		clear(es)
+	case m.alloc != nil:
+		es = m.alloc(n)
+	default:
+		es = make([]KeyValue[V], n)
+	}
+	if m.free != 0 {
+		m.clearEntries(es)
+	}
+	return es
+}
+
+// clearEntries marks all slots in es as free.
+//
+func (m *Map[V]) clearEntries(es []KeyValue[V]) {
+	clear(es)
+	if m.free != 0 {
+		for i := range es {
+			es[i].Key = m.free
+		}
+	}
+}
+
+// freeEntries releases a backing array that is no longer in use.
+//
+func (m *Map[V]) freeEntries(es []KeyValue[V]) {
+	switch {
+	case len(es) == 0 || m.isInline(es):
+	case &es[0] == m.userBuf:
+		// not ours to release
+		m.userBuf = nil
+	case m.release != nil:
+		m.release(es)
+	}
+}
+
+func (m *Map[V]) isInline(es []KeyValue[V]) bool {
+	return len(es) > 0 && &es[0] == &m.inline[0]
+}
+
+// Get returns the value associated with the given key and ok set to true if the key exists.
+// If the keys does not exist, it returns the zero value for the Value type and false.
+//
+func (m *Map[V]) Get(key int) (v V, ok bool) {
+	if key == m.free {
+		if m.hasFreeKey {
+			return m.freeKeyValue, true
+		}
+		return v, false
+	}
+	if len(m.es) <= linearScanCap {
+		// tiny map: a linear scan of one or two cache lines is faster than
+		// hashing.
+		for i := range m.es {
+			if t := &m.es[i]; t.Key == key {
+				return t.Value, true
+			}
+		}
+		return v, false
+	}
+	mod := len(m.es) - 1
+	idx := m.slot(key, mod)
+	for step := 1; step <= len(m.es); step++ {
+		t := &m.es[idx]
+		switch t.Key {
+		case m.free:
+			if !m.isTomb(idx) {
+				return v, false
+			}
+		case key:
+			return t.Value, true
+		}
+		idx = m.next(idx, step, mod)
+	}
+	return v, false
+}
+
+// GetOr returns the value associated with the given key, or def if the key
+// does not exist.
+//
+func (m *Map[V]) GetOr(key int, def V) V {
+	if v, ok := m.Get(key); ok {
+		return v
+	}
+	return def
+}
+
+// MustGet returns the value associated with the given key. It panics if the key
+// does not exist.
+//
+func (m *Map[V]) MustGet(key int) V {
+	v, ok := m.Get(key)
+	if !ok {
+		panic("key " + strconv.Itoa(key) + " not found")
+	}
+	return v
+}
+
+// Has returns true if the given key exists in the map.
+//
+func (m *Map[V]) Has(key int) bool {
+	if key == m.free {
+		return m.hasFreeKey
+	}
+	if len(m.es) <= linearScanCap {
+		for i := range m.es {
+			if m.es[i].Key == key {
+				return true
+			}
+		}
+		return false
+	}
+	_, ok := m.probe(key)
+	return ok
+}
+
+// SwapKeys exchanges the values of keys a and b and returns true if both keys
+// exist. Otherwise the map is left unchanged and it returns false.
+//
+func (m *Map[V]) SwapKeys(a, b int) bool {
+	pa, ok := m.valuePtr(a)
+	if !ok {
+		return false
+	}
+	pb, ok := m.valuePtr(b)
+	if !ok {
+		return false
+	}
+	*pa, *pb = *pb, *pa
+	return true
+}
+
+// valuePtr returns a pointer to the value for key and true if the key exists.
+//
+func (m *Map[V]) valuePtr(key int) (*V, bool) {
+	if key == m.free {
+		return &m.freeKeyValue, m.hasFreeKey
+	}
+	idx, ok := m.probe(key)
+	if !ok {
+		return nil, false
+	}
+	return &m.es[idx].Value, true
+}
+
+// Delete deletes the given key and returns true if the key was present in the map.
+//
+func (m *Map[V]) Delete(key int) bool {
+	if key == m.free {
+		var zv V
+		rv := m.hasFreeKey
+		m.freeKeyValue = zv
+		m.hasFreeKey = false
+		if rv {
+			m.mods++
+			m.untrack(m.free)
+		}
+		return rv
+	}
+	idx, ok := m.probe(key)
+	if !ok {
+		return false
+	}
+	m.remove(idx)
+	return true
+}
+
+// GetAndDelete deletes the given key and returns its value and ok set to true
+// if the key was present in the map. If the key does not exist, it returns the
+// zero value for the Value type and false.
+//
+func (m *Map[V]) GetAndDelete(key int) (v V, ok bool) {
+	if key == m.free {
+		v, ok = m.freeKeyValue, m.hasFreeKey
+		m.Delete(m.free)
+		return v, ok
+	}
+	idx, ok := m.probe(key)
+	if !ok {
+		return v, false
+	}
+	v = m.es[idx].Value
+	m.remove(idx)
+	return v, true
+}
+
+// CompareAndDeleteFunc deletes the given key if it exists and its value is
+// equal to old according to eq. It reports whether the key was deleted.
+//
+func (m *Map[V]) CompareAndDeleteFunc(key int, old V, eq func(a, b V) bool) bool {
+	if key == m.free {
+		if !m.hasFreeKey || !eq(m.freeKeyValue, old) {
+			return false
+		}
+		return m.Delete(m.free)
+	}
+	idx, ok := m.probe(key)
+	if !ok || !eq(m.es[idx].Value, old) {
+		return false
+	}
+	m.remove(idx)
+	return true
+}
+
+// CompareAndDelete deletes the given key if it exists and its value is equal
+// to old. It reports whether the key was deleted.
+//
+func CompareAndDelete[V comparable](m *Map[V], key int, old V) bool {
+	return m.CompareAndDeleteFunc(key, old, equal[V])
+}
+
+// Pop deletes an arbitrary key from the map and returns it along with its value
+// and ok set to true. If the map is empty, it returns 0, the zero value for the
+// Value type and false.
+//
+func (m *Map[V]) Pop() (key int, value V, ok bool) {
+	if m.hasFreeKey {
+		value, _ = m.GetAndDelete(m.free)
+		return m.free, value, true
+	}
+	if m.size == 0 {
+		return 0, value, false
+	}
+	// resume scanning where the previous call left off so that draining the
+	// map does not repeatedly walk over the same free slots.
+	mod := len(m.es) - 1
+	i := m.popIdx & mod
+	for m.es[i].Key == m.free {
+		i = nextIdx(i) & mod
+	}
+	m.popIdx = i
+	key, value = m.es[i].Key, m.es[i].Value
+	m.remove(i)
+	return key, value, true
+}
+
+// probe returns the index of the slot holding key, which must not be m.free,
+// and true if the key is present. Otherwise it returns the index of the free
+// slot or tombstone where the key would be inserted, or -1 if there is none,
+// and false.
+//
+func (m *Map[V]) probe(key int) (idx int, found bool) {
+	mod := len(m.es) - 1
+	if mod < 0 {
+		return -1, false
+	}
+	idx = m.slot(key, mod)
+	tomb := -1
+	for step := 1; step <= len(m.es); step++ {
+		switch m.es[idx].Key {
+		case m.free:
+			if !m.isTomb(idx) {
+				if tomb >= 0 {
+					return tomb, false
+				}
+				return idx, false
+			}
+			if tomb < 0 {
+				tomb = idx
+			}
+		case key:
+			return idx, true
+		}
+		idx = m.next(idx, step, mod)
+	}
+	return tomb, false
+}
+
+// Compute looks up the given key and calls f with its current value and
+// whether it exists. If f returns keep set to true, the key is set to the
+// returned value, otherwise the key is deleted.
+//
+//	m.Compute(k, func(old int, _ bool) (int, bool) {
+//		return old + 1, true
+//	})
+//
+// f must not modify the map.
+//
+func (m *Map[V]) Compute(key int, f func(old V, exists bool) (v V, keep bool)) {
+	if key == m.free {
+		v, keep := f(m.freeKeyValue, m.hasFreeKey)
+		if keep {
+			m.setFreeKey(v)
+		} else {
+			m.Delete(m.free)
+		}
+		return
+	}
+	var old V
+	idx, exists := m.probe(key)
+	if exists {
+		old = m.es[idx].Value
+	}
+	v, keep := f(old, exists)
+	switch {
+	case exists && keep:
+		m.es[idx].Value = v
+	case exists:
+		m.remove(idx)
+	case keep:
+		idx = m.insertAt(key, idx)
+		m.es[idx].Value = v
+	}
+}
+
+// insertAt inserts key, which must not be m.free nor be in the map, in the slot
+// at idx returned by probe, or wherever reserve puts it if the map must first
+// be rehashed or reseeded. It returns the index of the slot holding key.
+//
+func (m *Map[V]) insertAt(key, idx int) int {
+	if idx < 0 || m.probeLimit > 0 {
+		// probe does not report the probe length needed for reseeding
+		idx, _ = m.reserve(key)
+		return idx
+	}
+	idx, _ = m.reserveFree(key, idx, 0)
+	return idx
+}
+
+// checkLen panics if a new key cannot be inserted in the map.
+//
+func (m *Map[V]) checkLen() {
+	if m.maxLen > 0 && m.Len() >= m.maxLen {
+		panic(ErrFull)
+	}
+}
+
+// insert sets key in the free slot at idx.
+//
+func (m *Map[V]) insert(idx, key int) {
+	if m.isTomb(idx) {
+		m.tombBits[idx/64] &^= 1 << (idx % 64)
+		m.tombs--
+	}
+	m.es[idx].Key = key
+	m.size++
+	m.mods++
+	m.track(key)
+}
+
+// remove deletes the key in the slot at idx.
+//
+func (m *Map[V]) remove(idx int) {
+	m.untrack(m.es[idx].Key)
+	if m.useTombs() {
+		m.bury(idx)
 	} else {
+		m.shiftKeys(idx)
+	}
+	m.size--
+	m.mods++
+}
+
+// bury replaces the key in the slot at idx with a tombstone.
+//
+func (m *Map[V]) bury(idx int) {
+	m.es[idx] = KeyValue[V]{Key: m.free}
+	mod := len(m.es) - 1
+	if !m.triangular {
+		if next := nextIdx(idx) & mod; m.es[next].Key == m.free && !m.isTomb(next) {
+			// No probe sequence goes past a free slot: the slot at idx and the
+			// tombstones before it can be freed.
+			for idx = (idx - 1) & mod; m.isTomb(idx); idx = (idx - 1) & mod {
+				m.tombBits[idx/64] &^= 1 << (idx % 64)
+				m.tombs--
+			}
+			return
+		}
+	}
+	if m.tombBits == nil {
+		m.tombBits = make([]uint64, (len(m.es)+63)/64)
+	}
+	m.tombBits[idx/64] |= 1 << (idx % 64)
+	m.tombs++
+}
+
+func (m *Map[V]) setFreeKey(v V) {
+	if !m.hasFreeKey {
+		m.checkLen()
+		m.hasFreeKey = true
+		m.mods++
+		m.track(m.free)
+	}
+	m.freeKeyValue = v
+}
+
+// shiftKeys is backShift inlined by hand: Delete is on the hot path and the
+// closures would cost about 10%.
+//
+func (m *Map[V]) shiftKeys(idx int) {
+	var k int
+	mod := len(m.es) - 1
+	for {
+		last := idx
+		idx = nextIdx(idx) & mod
+		for {
+			k = m.es[idx].Key
+			if k == m.free {
+				m.es[last] = KeyValue[V]{Key: m.free}
+				return
+			}
+			slot := m.slot(k, mod)
+			if last <= idx {
+				if last >= slot || slot > idx {
+					break
+				}
+			} else if last >= slot && slot > idx {
+				break
+			}
+			idx = nextIdx(idx) & mod
+		}
+		m.es[last] = KeyValue[V]{k, m.es[idx].Value}
+	}
+}
+
+// backShift deletes the key in slot idx of a linear probing table of mod+1
+// slots by moving back the keys that follow it in its cluster, unless that
+// would put them before their home slot. home returns the home slot of the key
+// in slot i, or -1 if slot i is free, and move moves the entry in slot src to
+// slot dst. backShift returns the slot left over at the end, which the caller
+// must clear.
+//
+// All the linear probing tables in this package delete keys with backShift,
+// except for Map, whose shiftKeys is a copy inlined by hand.
+//
+func backShift(idx, mod int, home func(i int) int, move func(dst, src int)) int {
+	for {
+		last := idx
+		idx = nextIdx(idx) & mod
+		for {
+			slot := home(idx)
+			if slot < 0 {
+				return last
+			}
+			if last <= idx {
+				if last >= slot || slot > idx {
+					break
+				}
+			} else if last >= slot && slot > idx {
+				break
+			}
+			idx = nextIdx(idx) & mod
+		}
+		move(last, idx)
+	}
+}
+
+// Len returns the number if keys set in the map.
+//
+func (m *Map[V]) Len() int {
+	if m.hasFreeKey {
+		return m.size + 1
+	}
+	return m.size
+}
+
+// Cap returns the capacity of the map, that is the number of slots in its
+// backing array.
+//
+func (m *Map[V]) Cap() int {
+	return len(m.es)
+}
+
+// LoadFactor returns the ratio of used slots to the map capacity. The free key
+// is stored separately and does not count as a used slot.
+//
+func (m *Map[V]) LoadFactor() float32 {
+	if len(m.es) == 0 {
+		return 0
+	}
+	return float32(m.size) / float32(len(m.es))
+}
+
+// Threshold returns the number of used slots above which the map capacity will
+// be doubled on the next insertion.
+//
+func (m *Map[V]) Threshold() int {
+	return m.threshold
+}
+
+// SizeBytes returns an estimate of the memory held by the map, in bytes,
+// including the Map structure itself and its backing array. Memory referenced
+// by the values, like the contents of slices or strings, is not accounted for.
+//
+func (m *Map[V]) SizeBytes() uintptr {
+	sz := unsafe.Sizeof(*m) + uintptr(cap(m.sortBuf))*unsafe.Sizeof(int(0)) + uintptr(cap(m.tombBits))*8
+	if !m.isInline(m.es) {
+		sz += uintptr(cap(m.es)) * unsafe.Sizeof(KeyValue[V]{})
+	}
+	return sz
+}
+
+// Keys returns an unordered slice of the map keys.
+//
+func (m *Map[V]) Keys() []int {
+	return m.AppendKeys(make([]int, 0, m.Len()))
+}
+
+// AppendKeys appends the map keys to dst in the same order as Keys and returns
+// the extended slice.
+//
+func (m *Map[V]) AppendKeys(dst []int) []int {
+	if m.hasFreeKey {
+		dst = append(dst, m.free)
+	}
+	es := m.es
+	for e := range es {
+		if k := es[e].Key; k != m.free {
+			dst = append(dst, k)
+		}
+	}
+	return dst
+}
+
+// Values returns a slice of the map values in the same order as Keys.
+//
+func (m *Map[V]) Values() []V {
+	return m.AppendValues(make([]V, 0, m.Len()))
+}
+
+// AppendValues appends the map values to dst in the same order as Keys and
+// returns the extended slice.
+//
+func (m *Map[V]) AppendValues(dst []V) []V {
+	if m.hasFreeKey {
+		dst = append(dst, m.freeKeyValue)
+	}
+	es := m.es
+	for e := range es {
+		if es[e].Key != m.free {
+			dst = append(dst, es[e].Value)
+		}
+	}
+	return dst
+}
+
+// Entries returns a slice of the map's key/value pairs in the same order as
+// Keys.
+//
+func (m *Map[V]) Entries() []KeyValue[V] {
+	kvs := make([]KeyValue[V], m.Len())
+	i := 0
+	if m.hasFreeKey {
+		kvs[i] = KeyValue[V]{m.free, m.freeKeyValue}
+		i++
+	}
+	es := m.es
+	for e := range es {
+		if es[e].Key != m.free {
+			kvs[i] = es[e]
+			i++
+		}
+	}
+	return kvs
+}
+
+// Nth returns the key/value pair at position i in the same order as Keys, and
+// true. If i is out of range, it returns a zero KeyValue and false.
+//
+// Nth scans the backing array up to the requested position; it is meant for
+// occasional random access, like paginating over a map that is not modified in
+// between calls.
+//
+func (m *Map[V]) Nth(i int) (KeyValue[V], bool) {
+	if i < 0 || i >= m.Len() {
+		return KeyValue[V]{}, false
+	}
+	if m.hasFreeKey {
+		if i == 0 {
+			return KeyValue[V]{m.free, m.freeKeyValue}, true
+		}
+		i--
+	}
+	es := m.es
+	for e := range es {
+		if es[e].Key != m.free {
+			if i == 0 {
+				return es[e], true
+			}
+			i--
+		}
+	}
+	panic("unreachable")
+}
+
+// UnsafeEntries returns the map's backing slice. It must be treated as
+// read-only and is only valid until the next insertion or deletion.
+//
+// The returned slice includes free slots, whose Key is the free key, 0 unless
+// set otherwise with SetFreeKey. Since the free key is stored separately, its
+// value, if any, must be retrieved with Get.
+//
+func (m *Map[V]) UnsafeEntries() []KeyValue[V] {
+	return m.es
+}
+
+// Range calls f sequentially for each key and value present in the map. If f
+// returns false, Range stops the iteration.
+//
+// f may change the value of any existing key, but must not insert or delete
+// keys.
+//
+func (m *Map[V]) Range(f func(key int, value V) bool) {
+	if m.hasFreeKey && !f(m.free, m.freeKeyValue) {
+		return
+	}
+	es := m.es
+	for e := range es {
+		if k := es[e].Key; k != m.free && !f(k, es[e].Value) {
+			return
+		}
+	}
+}
+
+// Iterator returns an iterator over the map's key/value pairs.
+//
+//	for i := m.Iterator(); i.HasNext(); {
+//		k, v := i.Next()
+//		fmt.Printf("m[%v] = %v\n", k, v)
+//	}
+//
+// While iterating over a map, deleting the last key returned by Next, either
+// with Iterator.Delete or Map.Delete, is supported as well of changing the value
+// of any existing key.
+// Inserting new keys or deleting any other keys will cause HasNext to panic.
+//
+// In most cases, ranging over All is simpler and does not allocate.
+//
+func (m *Map[V]) Iterator() *Iterator[V] {
+	var i Iterator[V]
+	i.Reset(m)
+	return &i
+}
+
+// SnapshotIterator returns an iterator over a copy of the map's key/value pairs
+// taken at the time of the call. The map can then be freely modified while
+// iterating, without affecting the iteration.
+//
+// Iterator.Delete on the returned iterator only affects the snapshot.
+//
+func (m *Map[V]) SnapshotIterator() *Iterator[V] {
+	return m.Clone().Iterator()
+}
+
+// Iterator represents an iterator over a map.
+//
+// Next returns the same key until HasNext is called. Once that key has been
+// deleted, with Iterator.Delete or Map.Delete, further calls to Next return it
+// with the zero value for V, although it is no longer in the map; callers that
+// need the value should keep the one returned by the first call.
+//
+type Iterator[V any] struct {
+	m       *Map[V]
+	lastKey int
+	i       int // number of slots walked, -1 while on the free key
+	start   int // index of the first slot to walk
+	mods    int // expected value of m.mods
+	seen    int  // number of keys returned by Next and still in the map
+	taken   bool // the key at the current position has been returned by Next
+	hasLast bool // lastKey has been returned by Next
+}
+
+// Reset resets the iterator to the start of the given map. This allows reusing
+// an Iterator, or using a zero Iterator, without further allocations.
+//
+func (i *Iterator[V]) Reset(m *Map[V]) {
+	// find a sensible default for
+	*i = Iterator[V]{m: m, lastKey: m.free ^ -1, i: -1, mods: m.mods}
+	// Walk the slots starting right after a free one: keys are only ever
+	// shifted back within a cluster when deleting, so no key can be moved
+	// from a slot already visited to one not yet visited.
+	es := m.es
+	for s := range es {
+		if es[s].Key == m.free {
+			i.start = s + 1
+			break
+		}
+	}
+}
+
+// Seek positions the iterator so that the next key returned by Next is the
+// given key if it is present in the map, or the key that follows the slot where
+// it would be otherwise. Seeking the free key is equivalent to a Reset.
+//
+// Seek can be used to resume an iteration from the last key seen, provided
+// that the map has not been modified in between.
+//
+func (i *Iterator[V]) Seek(key int) {
+	if key == i.m.free {
+		i.Reset(i.m)
+		return
+	}
+	idx, _ := i.m.probe(key)
+	if idx < 0 {
+		i.i = len(i.m.es)
+		i.mods = i.m.mods
+		i.seen = i.m.Len()
+		return
+	}
+	i.i = (idx - i.start) & (len(i.m.es) - 1)
+	i.lastKey = key ^ -1
+	i.hasLast = false
+	i.mods = i.m.mods
+	i.taken = false
+	i.seen = 0
+	if i.m.hasFreeKey {
+		i.seen++
+	}
+	for e := 0; e < i.i; e++ {
+		if i.m.es[i.slot(e)].Key != i.m.free {
+			i.seen++
+		}
+	}
+}
+
+func (i *Iterator[V]) slot(e int) int {
+	return (i.start + e) & (len(i.m.es) - 1)
+}
+
+// HasNext returns true if there are any keys left to read.
+//
+// HasNext panics if the map has been structurally modified since the last call
+// to Next in any other way than deleting the key it returned.
+//
+func (i *Iterator[V]) HasNext() bool {
+	i.checkMods()
+	es := i.m.es
+	l := len(es)
+	if i.i < 0 {
+		// first call
+		if i.m.hasFreeKey && i.lastKey != i.m.free {
+			return true
+		}
+		i.lastKey = i.m.free
+	} else if i.i < l {
 		// check for deletion of last key read by next
-		if k := i.m.es[i.i].Key; k != freeKey && k != i.lastKey {
+		if k := es[i.slot(i.i)].Key; k != i.m.free && k != i.lastKey {
+			i.taken = false
 			return true
 		}
 	}
 	for e := i.i + 1; e < l; e++ {
-		if k := es[e].Key; k != freeKey {
+		if k := es[i.slot(e)].Key; k != i.m.free {
 			i.i = e
+			i.taken = false
 			return true
 		}
 	}
@@ -350,16 +1795,87 @@ func (i *Iterator[V]) HasNext() bool {
 }
 
 // Next returns the next key/value pair. Calling Next several times in a row
-// without calling HasNext in between will yield the same result.
+// without calling HasNext in between will yield the same key. If that key has
+// been deleted in between, it is returned with the zero value for V.
 //
 func (i *Iterator[V]) Next() (key int, value V) {
 	if i.i < 0 {
 		if !i.m.hasFreeKey {
 			panic("Next() called without calling HasNext() first")
 		}
-		i.lastKey = freeKey
-		return freeKey, i.m.freeKeyValue
+		i.lastKey = i.m.free
+		i.hasLast = true
+		i.take()
+		return i.m.free, i.m.freeKeyValue
+	}
+	if i.i >= len(i.m.es) {
+		panic("Next() called after HasNext() returned false")
+	}
+	e := &i.m.es[i.slot(i.i)]
+	if i.taken && e.Key != i.lastKey {
+		// the key returned by the previous call has been deleted and another
+		// key shifted in its slot, which HasNext has not returned yet.
+		var zv V
+		return i.lastKey, zv
+	}
+	i.take()
+	i.lastKey = e.Key
+	i.hasLast = true
+	return i.lastKey, e.Value
+}
+
+// Delete deletes the last key returned by Next from the map. The iteration can
+// then safely proceed with HasNext.
+//
+func (i *Iterator[V]) Delete() {
+	if !i.hasLast {
+		return
+	}
+	if i.i < 0 {
+		if i.m.Delete(i.m.free) {
+			i.mods = i.m.mods
+			i.seen--
+		}
+		return
+	}
+	if i.i >= len(i.m.es) {
+		return
+	}
+	if s := i.slot(i.i); i.m.es[s].Key == i.lastKey {
+		i.m.remove(s)
+		i.mods = i.m.mods
+		i.seen--
+	}
+}
+
+func (i *Iterator[V]) take() {
+	if !i.taken {
+		i.taken = true
+		i.seen++
+	}
+}
+
+// Remaining returns the number of keys that have not yet been returned by Next.
+//
+func (i *Iterator[V]) Remaining() int {
+	i.checkMods()
+	return i.m.Len() - i.seen
+}
+
+// checkMods panics if the map has been modified in a way unsupported by the
+// iterator.
+//
+func (i *Iterator[V]) checkMods() {
+	switch i.m.mods - i.mods {
+	case 0:
+		return
+	case 1:
+		// deletion of the last key returned by Next
+		if i.hasLast && !i.m.Has(i.lastKey) {
+			i.mods = i.m.mods
+			i.seen--
+			return
+		}
 	}
-	i.lastKey = i.m.es[i.i].Key
-	return i.lastKey, i.m.es[i.i].Value
+	panic("map modified during iteration")
 }
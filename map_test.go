@@ -2,8 +2,14 @@ package intmap_test
 
 import (
 	"flag"
+	"hash/maphash"
+	"math"
 	"math/rand"
+	"math/bits"
+	"slices"
+	"strings"
 	"testing"
+	"unsafe"
 
 	"github.com/db47h/intmap"
 )
@@ -48,6 +54,30 @@ func BenchmarkIntMapGet(b *testing.B) {
 	}
 }
 
+func benchmarkIntMapGetSequential(b *testing.B, fibonacci bool) {
+	var m intmap.Map[Value]
+	m.SetFibonacciHashing(fibonacci)
+	m.Grow(*keyMax)
+	for i := 0; i < *keyMax; i++ {
+		m.Set(i*64, Value(i))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v, ok := m.Get((i % *keyMax) * 64)
+		if ok {
+			result = v
+		}
+	}
+}
+
+func BenchmarkIntMapGetSequential(b *testing.B) {
+	benchmarkIntMapGetSequential(b, false)
+}
+
+func BenchmarkIntMapGetSequentialFibonacci(b *testing.B) {
+	benchmarkIntMapGetSequential(b, true)
+}
+
 func BenchmarkBuiltinMapGet(b *testing.B) {
 	m := make(map[int]Value)
 	for i := 0; i < *keyMax; i++ {
@@ -87,9 +117,30 @@ func BenchmarkBuiltinMapDelete(b *testing.B) {
 	}
 }
 
+// mapConfigs are the map configurations exercised by the randomized tests.
+var mapConfigs = []struct {
+	name  string
+	setup func(m *intmap.Map[Value])
+}{
+	{"default", func(m *intmap.Map[Value]) {}},
+	{"fibonacci", func(m *intmap.Map[Value]) { m.SetFibonacciHashing(true) }},
+	{"triangular", func(m *intmap.Map[Value]) { m.SetTriangularProbing(true) }},
+	{"tombstones", func(m *intmap.Map[Value]) { m.SetTombstones(true) }},
+	{"free key", func(m *intmap.Map[Value]) { m.SetFreeKey(7) }},
+}
+
 func TestMap(t *testing.T) {
+	for _, cfg := range mapConfigs {
+		t.Run(cfg.name, func(t *testing.T) {
+			var mm intmap.Map[Value]
+			cfg.setup(&mm)
+			testMap(t, &mm)
+		})
+	}
+}
+
+func testMap(t *testing.T, mm *intmap.Map[Value]) {
 	rand.Seed(424242)
-	var mm intmap.Map[Value]
 	var sm = make(map[int]Value)
 
 	for i := 0; i < 1000000; i++ {
@@ -151,3 +202,1040 @@ func TestMap_Iter(t *testing.T) {
 		}
 	}
 }
+
+func TestMap_Swap(t *testing.T) {
+	var m intmap.Map[int]
+
+	for _, k := range []int{0, 42} {
+		if old, ok := m.Swap(k, 1); ok || old != 0 {
+			t.Errorf("Swap(%d, 1) = %v, %v; expected 0, false", k, old, ok)
+		}
+		if old, ok := m.Swap(k, 2); !ok || old != 1 {
+			t.Errorf("Swap(%d, 2) = %v, %v; expected 1, true", k, old, ok)
+		}
+		if v, _ := m.Get(k); v != 2 {
+			t.Errorf("bad value for key %d: expected 2, got %v", k, v)
+		}
+	}
+	if m.Len() != 2 {
+		t.Errorf("bad size: expected 2, got %d", m.Len())
+	}
+}
+
+func TestMap_GetAndDelete(t *testing.T) {
+	var m intmap.Map[int]
+
+	for _, k := range []int{0, 42} {
+		m.Set(k, k+1)
+		if v, ok := m.GetAndDelete(k); !ok || v != k+1 {
+			t.Errorf("GetAndDelete(%d) = %v, %v; expected %v, true", k, v, ok, k+1)
+		}
+		if v, ok := m.GetAndDelete(k); ok || v != 0 {
+			t.Errorf("GetAndDelete(%d) = %v, %v; expected 0, false", k, v, ok)
+		}
+	}
+	if m.Len() != 0 {
+		t.Errorf("bad size: expected 0, got %d", m.Len())
+	}
+}
+
+func TestMap_Compute(t *testing.T) {
+	var m intmap.Map[int]
+	inc := func(old int, _ bool) (int, bool) { return old + 1, true }
+
+	for i := 0; i < 100; i++ {
+		for k := 0; k < 10; k++ {
+			m.Compute(k, inc)
+		}
+	}
+	for k := 0; k < 10; k++ {
+		if v, _ := m.Get(k); v != 100 {
+			t.Errorf("bad value for key %d: expected 100, got %v", k, v)
+		}
+		m.Compute(k, func(old int, exists bool) (int, bool) {
+			if !exists {
+				t.Errorf("key %d not found", k)
+			}
+			return 0, false
+		})
+	}
+	if m.Len() != 0 {
+		t.Errorf("bad size: expected 0, got %d", m.Len())
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	var m intmap.Map[int]
+
+	for _, k := range []int{0, 42} {
+		if intmap.CompareAndSwap(&m, k, 0, 1) {
+			t.Errorf("CompareAndSwap(%d, 0, 1) succeeded on missing key", k)
+		}
+		m.Set(k, 1)
+		if intmap.CompareAndSwap(&m, k, 2, 3) {
+			t.Errorf("CompareAndSwap(%d, 2, 3) succeeded on value 1", k)
+		}
+		if !intmap.CompareAndSwap(&m, k, 1, 2) {
+			t.Errorf("CompareAndSwap(%d, 1, 2) failed on value 1", k)
+		}
+		if v, _ := m.Get(k); v != 2 {
+			t.Errorf("bad value for key %d: expected 2, got %v", k, v)
+		}
+	}
+}
+
+func TestCompareAndDelete(t *testing.T) {
+	var m intmap.Map[int]
+
+	for _, k := range []int{0, 42} {
+		m.Set(k, 1)
+		if intmap.CompareAndDelete(&m, k, 2) {
+			t.Errorf("CompareAndDelete(%d, 2) succeeded on value 1", k)
+		}
+		if !intmap.CompareAndDelete(&m, k, 1) {
+			t.Errorf("CompareAndDelete(%d, 1) failed on value 1", k)
+		}
+		if intmap.CompareAndDelete(&m, k, 1) {
+			t.Errorf("CompareAndDelete(%d, 1) succeeded on missing key", k)
+		}
+	}
+	if m.Len() != 0 {
+		t.Errorf("bad size: expected 0, got %d", m.Len())
+	}
+}
+
+func TestMap_Clear(t *testing.T) {
+	m := intmap.New[int](16, 0.5)
+	for k := 0; k < 100; k++ {
+		m.Set(k, k)
+	}
+	m.Clear()
+	if m.Len() != 0 {
+		t.Errorf("bad size: expected 0, got %d", m.Len())
+	}
+	for k := 0; k < 100; k++ {
+		if _, ok := m.Get(k); ok {
+			t.Errorf("key %d found after Clear", k)
+		}
+	}
+	m.Set(42, 1)
+	if v, ok := m.Get(42); !ok || v != 1 {
+		t.Errorf("Get(42) = %v, %v; expected 1, true", v, ok)
+	}
+}
+
+func TestMap_Clone(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 100; k++ {
+		m.Set(k, k)
+	}
+	c := m.Clone()
+	m.Set(0, -1)
+	m.Delete(42)
+	if c.Len() != 100 {
+		t.Fatalf("bad size: expected 100, got %d", c.Len())
+	}
+	for k := 0; k < 100; k++ {
+		if v, ok := c.Get(k); !ok || v != k {
+			t.Errorf("Get(%d) = %v, %v; expected %v, true", k, v, ok, k)
+		}
+	}
+}
+
+func TestMap_Has(t *testing.T) {
+	var m intmap.Map[int]
+	for _, k := range []int{0, 42} {
+		if m.Has(k) {
+			t.Errorf("Has(%d) = true on empty map", k)
+		}
+		m.Set(k, k)
+		if !m.Has(k) {
+			t.Errorf("Has(%d) = false after Set", k)
+		}
+	}
+}
+
+func TestMap_GetOr(t *testing.T) {
+	var m intmap.Map[int]
+	m.Set(42, 1)
+	if v := m.GetOr(42, -1); v != 1 {
+		t.Errorf("GetOr(42, -1) = %d; expected 1", v)
+	}
+	if v := m.GetOr(0, -1); v != -1 {
+		t.Errorf("GetOr(0, -1) = %d; expected -1", v)
+	}
+}
+
+func TestMap_MustGet(t *testing.T) {
+	var m intmap.Map[int]
+	m.Set(42, 1)
+	if v := m.MustGet(42); v != 1 {
+		t.Errorf("MustGet(42) = %d; expected 1", v)
+	}
+	defer func() {
+		if r := recover(); r != "key 21 not found" {
+			t.Errorf("unexpected panic value %v", r)
+		}
+	}()
+	m.MustGet(21)
+	t.Error("MustGet(21) did not panic")
+}
+
+func TestMap_Values(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 100; k++ {
+		m.Set(k, -k)
+	}
+	ks, vs, kvs := m.Keys(), m.Values(), m.Entries()
+	if len(ks) != 100 || len(vs) != 100 || len(kvs) != 100 {
+		t.Fatalf("bad lengths: %d, %d, %d", len(ks), len(vs), len(kvs))
+	}
+	for i, k := range ks {
+		if vs[i] != -k || kvs[i].Key != k || kvs[i].Value != -k {
+			t.Errorf("bad entry %d for key %d: %d, %v", i, k, vs[i], kvs[i])
+		}
+	}
+}
+
+func TestMap_AppendKeys(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 10; k++ {
+		m.Set(k, -k)
+	}
+	ks := m.AppendKeys([]int{-1})
+	vs := m.AppendValues([]int{1})
+	if len(ks) != 11 || len(vs) != 11 {
+		t.Fatalf("bad lengths: %d, %d", len(ks), len(vs))
+	}
+	for i := range ks {
+		if vs[i] != -ks[i] {
+			t.Errorf("bad value for key %d: %d", ks[i], vs[i])
+		}
+	}
+}
+
+func TestMap_Range(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 100; k++ {
+		m.Set(k, k)
+	}
+	sum, n := 0, 0
+	m.Range(func(k, v int) bool {
+		sum += v
+		n++
+		return true
+	})
+	if sum != 4950 || n != 100 {
+		t.Errorf("bad sum or count: %d, %d", sum, n)
+	}
+	n = 0
+	m.Range(func(k, v int) bool {
+		n++
+		return n < 10
+	})
+	if n != 10 {
+		t.Errorf("Range did not stop: %d calls", n)
+	}
+}
+
+func TestMap_Pop(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 1000; k++ {
+		m.Set(k, -k)
+	}
+	seen := make(map[int]bool)
+	for {
+		k, v, ok := m.Pop()
+		if !ok {
+			break
+		}
+		if v != -k || seen[k] {
+			t.Fatalf("Pop() = %d, %d", k, v)
+		}
+		seen[k] = true
+	}
+	if len(seen) != 1000 || m.Len() != 0 {
+		t.Errorf("bad sizes: popped %d keys, %d left", len(seen), m.Len())
+	}
+}
+
+func TestMap_SwapKeys(t *testing.T) {
+	var m intmap.Map[int]
+	m.Set(0, 1)
+	m.Set(42, 2)
+	if m.SwapKeys(0, 21) || m.SwapKeys(21, 42) {
+		t.Error("SwapKeys succeeded with a missing key")
+	}
+	if !m.SwapKeys(0, 42) {
+		t.Error("SwapKeys(0, 42) failed")
+	}
+	if v0, v42 := m.MustGet(0), m.MustGet(42); v0 != 2 || v42 != 1 {
+		t.Errorf("bad values after swap: %d, %d", v0, v42)
+	}
+}
+
+func TestIterator_Delete(t *testing.T) {
+	m := intmap.New[int](128, 0.99)
+	for k := 0; k < 120; k++ {
+		m.Set(k*128, k)
+	}
+	m.Set(0, 0)
+	seen := make(map[int]int)
+	for i := m.Iterator(); i.HasNext(); {
+		k, v := i.Next()
+		seen[k]++
+		if v%2 == 0 {
+			i.Delete()
+			i.Delete() // No-op
+		}
+	}
+	if len(seen) != 120 {
+		t.Errorf("bad number of keys visited: expected 120, got %d", len(seen))
+	}
+	for k, n := range seen {
+		if n != 1 {
+			t.Errorf("key %d visited %d times", k, n)
+		}
+	}
+	if m.Len() != 60 {
+		t.Errorf("bad size: expected 60, got %d", m.Len())
+	}
+}
+
+func TestIterator_Reset(t *testing.T) {
+	var a, b intmap.Map[int]
+	for k := 0; k < 10; k++ {
+		a.Set(k, k)
+		b.Set(k+10, k)
+	}
+	var it intmap.Iterator[int]
+	for _, m := range []*intmap.Map[int]{&a, &b, &a} {
+		n := 0
+		for it.Reset(m); it.HasNext(); {
+			k, _ := it.Next()
+			if !m.Has(k) {
+				t.Errorf("unexpected key %d", k)
+			}
+			n++
+		}
+		if n != 10 {
+			t.Errorf("bad number of keys: expected 10, got %d", n)
+		}
+	}
+}
+
+func TestIterator_Seek(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 100; k++ {
+		m.Set(k, k)
+	}
+	var ks []int
+	for i := m.Iterator(); i.HasNext(); {
+		k, _ := i.Next()
+		ks = append(ks, k)
+	}
+	i := m.Iterator()
+	for n, k := range ks {
+		i.Seek(k)
+		for _, exp := range ks[n:] {
+			if !i.HasNext() {
+				t.Fatalf("iterator exhausted after Seek(%d)", k)
+			}
+			if kk, _ := i.Next(); kk != exp {
+				t.Fatalf("bad key after Seek(%d): expected %d, got %d", k, exp, kk)
+			}
+		}
+		if i.HasNext() {
+			t.Fatalf("extra keys after Seek(%d)", k)
+		}
+	}
+}
+
+func TestIterator_failFast(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 10; k++ {
+		m.Set(k, k)
+	}
+	// supported modifications
+	for i := m.Iterator(); i.HasNext(); {
+		k, v := i.Next()
+		m.Set(k, v+1)
+		if k%2 == 0 {
+			m.Delete(k)
+		}
+	}
+
+	defer func() {
+		if r := recover(); r != "map modified during iteration" {
+			t.Errorf("unexpected panic value %v", r)
+		}
+	}()
+	for i := m.Iterator(); i.HasNext(); {
+		k, _ := i.Next()
+		m.Set(k+100, 0)
+	}
+	t.Error("insertion during iteration not detected")
+}
+
+func TestIterator_SetAtThreshold(t *testing.T) {
+	for _, cfg := range mapConfigs {
+		t.Run(cfg.name, func(t *testing.T) {
+			var m intmap.Map[Value]
+			cfg.setup(&m)
+			// fill a zero map up to its threshold
+			for k := 1; k <= 7; k++ {
+				m.Set(k, Value(k))
+			}
+			n := 0
+			for i := m.Iterator(); i.HasNext(); {
+				k, _ := i.Next()
+				m.Set(k, 100)
+				n++
+			}
+			if n != 7 {
+				t.Errorf("iterated over %d keys, expected 7", n)
+			}
+		})
+	}
+}
+
+func TestMap_SnapshotIterator(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 10; k++ {
+		m.Set(k, k)
+	}
+	n := 0
+	for i := m.SnapshotIterator(); i.HasNext(); {
+		k, v := i.Next()
+		if k != v {
+			t.Errorf("bad value for key %d: %d", k, v)
+		}
+		m.Delete(k)
+		m.Set(k+100, k)
+		n++
+	}
+	if n != 10 || m.Len() != 10 {
+		t.Errorf("bad counts: %d iterations, %d keys", n, m.Len())
+	}
+}
+
+func TestIterator_Remaining(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 10; k++ {
+		m.Set(k, k)
+	}
+	i := m.Iterator()
+	for n := 10; i.HasNext(); n-- {
+		if r := i.Remaining(); r != n {
+			t.Fatalf("Remaining() = %d; expected %d", r, n)
+		}
+		k, _ := i.Next()
+		i.Next() // No-op
+		if r := i.Remaining(); r != n-1 {
+			t.Fatalf("Remaining() = %d; expected %d", r, n-1)
+		}
+		if k%2 == 0 {
+			i.Delete()
+		} else if k%3 == 0 {
+			m.Delete(k)
+		}
+		if r := i.Remaining(); r != n-1 {
+			t.Fatalf("Remaining() = %d after deletion; expected %d", r, n-1)
+		}
+	}
+}
+
+func TestMap_UnsafeEntries(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 100; k++ {
+		m.Set(k, k)
+	}
+	sum, n := 0, 0
+	for _, e := range m.UnsafeEntries() {
+		if e.Key != 0 {
+			sum += e.Value
+			n++
+		}
+	}
+	if n != 99 || sum != 4950 {
+		t.Errorf("bad count or sum: %d, %d", n, sum)
+	}
+}
+
+func TestMap_SetDeterministicOrder(t *testing.T) {
+	var a, b intmap.Map[int]
+	b.SetDeterministicOrder(true)
+	for k := -10; k <= 10; k++ {
+		a.Set(k*1000, k)
+		b.Set(k*1000, k)
+	}
+	a.SetDeterministicOrder(true)
+	exp := []int{0, 2000, -9000, 4000, -7000, 6000, 7000, 8000, 9000, 10000, 1000,
+		-10000, 3000, -8000, 5000, -6000, -5000, -4000, -3000, -2000, -1000}
+	for _, m := range []*intmap.Map[int]{&a, &b} {
+		ks := m.Keys()
+		if len(ks) != len(exp) {
+			t.Fatalf("bad length: expected %d, got %d", len(exp), len(ks))
+		}
+		for i := range exp {
+			if ks[i] != exp[i] {
+				t.Fatalf("bad key order: expected %v, got %v", exp, ks)
+			}
+		}
+	}
+}
+
+func TestMap_Nth(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 100; k++ {
+		m.Set(k, -k)
+	}
+	for i, k := range m.Keys() {
+		if kv, ok := m.Nth(i); !ok || kv.Key != k || kv.Value != -k {
+			t.Errorf("Nth(%d) = %v, %v; expected key %d", i, kv, ok, k)
+		}
+	}
+	if _, ok := m.Nth(100); ok {
+		t.Error("Nth(100) succeeded")
+	}
+	if _, ok := m.Nth(-1); ok {
+		t.Error("Nth(-1) succeeded")
+	}
+}
+
+func TestMap_Grow(t *testing.T) {
+	var m intmap.Map[int]
+	m.Set(-1, -1)
+	m.Grow(1000)
+	es := m.UnsafeEntries()
+	for k := 0; k < 1000; k++ {
+		m.Set(k, k)
+	}
+	if &m.UnsafeEntries()[0] != &es[0] {
+		t.Error("map rehashed after Grow")
+	}
+	if v, _ := m.Get(-1); v != -1 {
+		t.Errorf("bad value for key -1: %d", v)
+	}
+}
+
+func TestMap_Cap(t *testing.T) {
+	m := intmap.New[int](16, 0.5)
+	if m.Cap() != 16 || m.Threshold() != 8 || m.LoadFactor() != 0 {
+		t.Errorf("bad initial state: %d, %d, %v", m.Cap(), m.Threshold(), m.LoadFactor())
+	}
+	for k := 1; k <= 9; k++ {
+		m.Set(k, k)
+	}
+	if m.Cap() != 32 || m.Threshold() != 16 || m.LoadFactor() != 9.0/32 {
+		t.Errorf("bad state after growth: %d, %d, %v", m.Cap(), m.Threshold(), m.LoadFactor())
+	}
+}
+
+func TestMap_Shrink(t *testing.T) {
+	m := intmap.New[int](16, 0.5)
+	for k := 0; k < 1000; k++ {
+		m.Set(k, k)
+	}
+	for k := 10; k < 1000; k++ {
+		m.Delete(k)
+	}
+	m.Shrink()
+	if m.Cap() != 32 || m.Threshold() != 16 {
+		t.Errorf("bad state after Shrink: %d, %d", m.Cap(), m.Threshold())
+	}
+	for k := 0; k < 10; k++ {
+		if v, ok := m.Get(k); !ok || v != k {
+			t.Errorf("Get(%d) = %v, %v", k, v, ok)
+		}
+	}
+}
+
+func TestMap_SetAutoShrink(t *testing.T) {
+	var m intmap.Map[int]
+	m.SetAutoShrink(0.1)
+	for k := 0; k < 1000; k++ {
+		m.Set(k, k)
+	}
+	c := m.Cap()
+	for k := 100; k < 1000; k++ {
+		m.Delete(k)
+	}
+	if m.Cap() != c {
+		t.Errorf("map shrunk on Delete")
+	}
+	m.Set(1000, 1000)
+	if m.Cap() >= c || m.LoadFactor() > 0.5 {
+		t.Errorf("bad state after shrinking: %d, %v", m.Cap(), m.LoadFactor())
+	}
+	if n := m.DeleteMany(m.Keys()[2:]); n != 99 || m.Cap() != 16 {
+		t.Errorf("bad state after DeleteMany: %d, %d", n, m.Cap())
+	}
+	if m.Len() != 2 {
+		t.Errorf("bad size: expected 2, got %d", m.Len())
+	}
+}
+
+func TestMap_SetFillRatio(t *testing.T) {
+	m := intmap.New[int](64, 0.9)
+	for k := 0; k < 50; k++ {
+		m.Set(k, k)
+	}
+	m.SetFillRatio(0.5)
+	if m.Cap() != 128 || m.Threshold() != 64 {
+		t.Errorf("bad state: %d, %d", m.Cap(), m.Threshold())
+	}
+	m.SetFillRatio(0.25)
+	if m.Cap() != 256 || m.Threshold() != 64 {
+		t.Errorf("bad state: %d, %d", m.Cap(), m.Threshold())
+	}
+	for k := 0; k < 50; k++ {
+		if v, _ := m.Get(k); v != k {
+			t.Errorf("bad value for key %d: %d", k, v)
+		}
+	}
+	var z intmap.Map[int]
+	z.Set(0, 1)
+	z.SetFillRatio(0.5)
+	if z.Cap() != 8 || z.Threshold() != 4 || z.Len() != 1 {
+		t.Errorf("bad state: %d, %d, %d", z.Cap(), z.Threshold(), z.Len())
+	}
+}
+
+func TestMap_SetMaxLen(t *testing.T) {
+	var m intmap.Map[int]
+	m.SetMaxLen(10)
+	for k := 0; k < 10; k++ {
+		if err := m.TrySet(k, k); err != nil {
+			t.Fatalf("TrySet(%d) = %v", k, err)
+		}
+	}
+	if err := m.TrySet(10, 10); err != intmap.ErrFull {
+		t.Errorf("TrySet(10) = %v; expected ErrFull", err)
+	}
+	if err := m.TrySet(0, 1); err != nil {
+		t.Errorf("TrySet(0) = %v on existing key", err)
+	}
+	func() {
+		defer func() {
+			if r := recover(); r != intmap.ErrFull {
+				t.Errorf("unexpected panic value %v", r)
+			}
+		}()
+		m.Set(10, 10)
+		t.Error("Set did not panic on a full map")
+	}()
+	if m.Len() != 10 || m.Has(10) {
+		t.Errorf("bad map state: %d keys", m.Len())
+	}
+	m.SetMaxLen(0)
+	m.Set(10, 10)
+}
+
+func TestMap_SetMaxLenRehash(t *testing.T) {
+	for _, withZero := range []bool{false, true} {
+		var m intmap.Map[int]
+		for k := 1; k <= 100; k++ {
+			m.Set(k, k)
+		}
+		if withZero {
+			m.Set(0, 0)
+		}
+		n := m.Len()
+		for _, limit := range []int{50, n} {
+			m.SetMaxLen(limit)
+			// rehashes only move keys around and must not hit the limit
+			m.SetSeed(1)
+			m.Shrink()
+			m.SetFreeKey(-1)
+			m.SetDeterministicOrder(true)
+			m.SetFreeKey(0)
+			m.Grow(1000)
+			if m.Len() != n {
+				t.Fatalf("limit %d: Len() = %d, expected %d", limit, m.Len(), n)
+			}
+			if err := m.TrySet(1000, 0); err != intmap.ErrFull {
+				t.Errorf("limit %d: TrySet(1000) = %v; expected ErrFull", limit, err)
+			}
+		}
+	}
+}
+
+func TestMap_ResetSeed(t *testing.T) {
+	keys := func(m *intmap.Map[int]) []int {
+		for k := 1; k <= 40; k++ {
+			m.Set(k*7919, k)
+		}
+		return m.Keys()
+	}
+	want := keys(intmap.New[int](64, 0.75))
+	// a reset map must be unseeded, like a new one
+	m := intmap.New[int](64, 0.75)
+	m.SetSeed(42)
+	if slices.Equal(keys(m), want) {
+		t.Fatal("same key order with seed 42 and unseeded")
+	}
+	m.Reset()
+	if got := keys(m); !slices.Equal(got, want) {
+		t.Errorf("reset map keys %v, expected %v", got, want)
+	}
+}
+
+func TestMap_SizeBytes(t *testing.T) {
+	var m intmap.Map[int64]
+	base := m.SizeBytes()
+	m.Init(1024, 0.5)
+	if sz := m.SizeBytes() - base; sz != 1024*unsafe.Sizeof(intmap.KeyValue[int64]{}) {
+		t.Errorf("bad size: %d", sz)
+	}
+}
+
+func TestMap_Reset(t *testing.T) {
+	m := intmap.New[int](64, 0.5)
+	m.SetMaxLen(10)
+	m.SetDeterministicOrder(true)
+	for k := 0; k < 10; k++ {
+		m.Set(k, k)
+	}
+	es := m.UnsafeEntries()
+	m.Reset()
+	if m.Len() != 0 || m.Cap() != 64 || &m.UnsafeEntries()[0] != &es[0] {
+		t.Fatalf("bad state after Reset: %d, %d", m.Len(), m.Cap())
+	}
+	if allocs := testing.AllocsPerRun(10, m.Reset); allocs != 0 {
+		t.Errorf("Reset allocates: %v", allocs)
+	}
+	for k := 0; k < 20; k++ {
+		m.Set(k, k)
+	}
+}
+
+func TestMap_SetAllocator(t *testing.T) {
+	var m intmap.Map[int]
+	live := make(map[*intmap.KeyValue[int]]bool)
+	m.SetAllocator(func(n int) []intmap.KeyValue[int] {
+		es := make([]intmap.KeyValue[int], n)
+		live[&es[0]] = true
+		return es
+	}, func(es []intmap.KeyValue[int]) {
+		if !live[&es[0]] {
+			t.Errorf("release of unknown or already released array")
+		}
+		delete(live, &es[0])
+	})
+	for k := 0; k < 1000; k++ {
+		m.Set(k, k)
+	}
+	m.Shrink()
+	if len(live) != 1 || !live[&m.UnsafeEntries()[0]] {
+		t.Errorf("bad live array count: %d", len(live))
+	}
+	for k := 0; k < 1000; k++ {
+		if v, _ := m.Get(k); v != k {
+			t.Errorf("bad value for key %d: %d", k, v)
+		}
+	}
+}
+
+func TestMap_InitBuffer(t *testing.T) {
+	var (
+		m   intmap.Map[int]
+		buf [16]intmap.KeyValue[int]
+	)
+	if err := m.InitBuffer(buf[:12], 0.5); err == nil {
+		t.Error("InitBuffer succeeded with a buffer of length 12")
+	}
+	if err := m.InitBuffer(buf[:], 0.5); err != nil {
+		t.Fatal(err)
+	}
+	for k := 0; k < 20; k++ {
+		m.Set(k, k)
+		if k == 8 && &m.UnsafeEntries()[0] != &buf[0] {
+			t.Error("buffer not used")
+		}
+	}
+	for k := 0; k < 20; k++ {
+		if v, _ := m.Get(k); v != k {
+			t.Errorf("bad value for key %d: %d", k, v)
+		}
+	}
+}
+
+func TestMap_inline(t *testing.T) {
+	allocs := testing.AllocsPerRun(10, func() {
+		var m intmap.Map[int]
+		for k := 0; k < 8; k++ {
+			m.Set(k, k)
+		}
+		if m.Cap() != 8 || m.MustGet(7) != 7 {
+			t.Fatalf("bad capacity or value: %d", m.Cap())
+		}
+	})
+	// m itself may escape to the heap
+	if allocs > 1 {
+		t.Errorf("too many allocations: %v", allocs)
+	}
+}
+
+func BenchmarkIntMapGetTiny(b *testing.B) {
+	var m intmap.Map[Value]
+	for i := 0; i < 12; i++ {
+		m.Set(i, Value(i))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v, ok := m.Get(i & 15)
+		if ok {
+			result = v
+		}
+	}
+}
+
+func TestMap_SetSeed(t *testing.T) {
+	var a, b, c intmap.Map[int]
+	a.SetSeed(42)
+	c.SetSeed(42)
+	for k := 0; k < 100; k++ {
+		a.Set(k, k)
+		b.Set(k, k)
+		c.Set(k, k)
+	}
+	b.SetSeed(42)
+	ka, kb := a.Keys(), c.Keys()
+	for i := range ka {
+		if ka[i] != kb[i] {
+			t.Fatalf("different key order with the same seed: %v, %v", ka, kb)
+		}
+	}
+	for k := 0; k < 100; k++ {
+		if v, _ := b.Get(k); v != k {
+			t.Errorf("bad value for key %d: %d", k, v)
+		}
+	}
+}
+
+func TestMap_SeedHighBits(t *testing.T) {
+	// keys that only differ in their high bits must not collide under every
+	// seed.
+	for _, fib := range []bool{false, true} {
+		for _, seed := range []uint64{1, 42, 0xdeadbeef} {
+			var m intmap.Map[int]
+			m.SetFibonacciHashing(fib)
+			m.SetSeed(seed)
+			for j := 1; j <= 2000; j++ {
+				m.Set(j<<(bits.UintSize-20), j)
+			}
+			if p := m.Stats().MaxProbe; p > 64 {
+				t.Errorf("fibonacci: %v, seed %#x: max probe length %d", fib, seed, p)
+			}
+		}
+	}
+}
+
+func TestMap_SetMaphashSeed(t *testing.T) {
+	seed := maphash.MakeSeed()
+	var a, b intmap.Map[int]
+	a.SetMaphashSeed(seed)
+	b.SetMaphashSeed(seed)
+	for k := 0; k < 100; k++ {
+		a.Set(k, k)
+		b.Set(k, k)
+	}
+	ka, kb := a.Keys(), b.Keys()
+	for i := range ka {
+		if ka[i] != kb[i] {
+			t.Fatalf("different key order with the same seed: %v, %v", ka, kb)
+		}
+	}
+}
+
+func TestMap_SetProbeLimit(t *testing.T) {
+	m := intmap.New[int](16, 0.95)
+	reseeds := 0
+	m.SetProbeLimit(2, func(probe int) {
+		if probe <= 2 {
+			t.Errorf("reseed with probe length %d", probe)
+		}
+		reseeds++
+	})
+	for k := 0; k < 1000; k++ {
+		m.Set(k*1024, k)
+	}
+	if reseeds == 0 {
+		t.Error("no reseed")
+	}
+	for k := 0; k < 1000; k++ {
+		if v, ok := m.Get(k * 1024); !ok || v != k {
+			t.Errorf("bad value for key %d: %d, %v", k*1024, v, ok)
+		}
+	}
+}
+
+func TestMap_InsertMaintenance(t *testing.T) {
+	// all insertion methods must shrink and reseed the map like Set
+	for name, insert := range map[string]func(m *intmap.Map[int], k int){
+		"Set": func(m *intmap.Map[int], k int) { m.Set(k, k) },
+		"Compute": func(m *intmap.Map[int], k int) {
+			m.Compute(k, func(int, bool) (int, bool) { return k, true })
+		},
+		"OrInsert": func(m *intmap.Map[int], k int) { m.Entry(k).OrInsert(k) },
+	} {
+		m := intmap.New[int](1024, 0.875)
+		m.SetAutoShrink(0.1)
+		for k := 1; k <= 500; k++ {
+			m.Set(k, k)
+		}
+		for k := 1; k <= 490; k++ {
+			m.Delete(k)
+		}
+		insert(m, 1000)
+		if m.Cap() >= 1024 {
+			t.Errorf("%s: capacity %d, expected the map to shrink", name, m.Cap())
+		}
+
+		m = intmap.New[int](16, 0.95)
+		reseeds := 0
+		m.SetProbeLimit(2, func(int) { reseeds++ })
+		for k := 0; k < 1000; k++ {
+			insert(m, k*1024)
+		}
+		if reseeds == 0 {
+			t.Errorf("%s: no reseed", name)
+		}
+		for k := 0; k < 1000; k++ {
+			if v, ok := m.Get(k * 1024); !ok || v != k*1024 {
+				t.Errorf("%s: bad value for key %d: %d, %v", name, k*1024, v, ok)
+			}
+		}
+	}
+}
+
+func TestMap_SetProbeLimitBackoff(t *testing.T) {
+	// A limit that nearly every insertion exceeds must not trigger a reseed,
+	// and a full rehash, on each of them.
+	m := intmap.New[int](16, 0.95)
+	reseeds := 0
+	m.SetProbeLimit(1, func(int) { reseeds++ })
+	const n = 3000
+	for j := 1; j <= n; j++ {
+		m.Set(j<<(bits.UintSize-20), j)
+	}
+	if max := bits.Len(uint(m.Cap())); reseeds > max {
+		t.Errorf("%d reseeds for %d keys, expected at most %d", reseeds, n, max)
+	}
+	if m.Len() != n {
+		t.Errorf("Len() = %d, expected %d", m.Len(), n)
+	}
+}
+
+func countTombstones(t *testing.T, m *intmap.Map[int]) int {
+	var sb strings.Builder
+	if err := m.DebugDump(&sb); err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(sb.String(), ": x\n")
+}
+
+func TestMap_CompactTombstones(t *testing.T) {
+	var m intmap.Map[int]
+	m.SetTombstones(true)
+	m.SetSeed(0)
+	for k := 0; k < 1000; k++ {
+		m.Set(k, k)
+	}
+	for k := 0; k < 1000; k += 2 {
+		m.Delete(k)
+	}
+	if countTombstones(t, &m) == 0 {
+		t.Fatal("no tombstones")
+	}
+	m.CompactTombstones()
+	if n := countTombstones(t, &m); n != 0 {
+		t.Fatalf("%d tombstones left after CompactTombstones", n)
+	}
+
+	m.SetCompactRatio(0.0001)
+	m.DeleteMany([]int{1, 3, 5, 7, 9, 11, 13, 15, 17, 19, 21, 23, 25, 27, 29, 31, 33, 35, 37, 39, 41})
+	if n := countTombstones(t, &m); n != 0 {
+		t.Errorf("%d tombstones left after DeleteMany", n)
+	}
+	for k := 0; k < 1000; k++ {
+		if v, ok := m.Get(k); ok != (k&1 != 0 && k > 41) || (ok && v != k) {
+			t.Errorf("bad value for key %d: %d, %v", k, v, ok)
+		}
+	}
+}
+
+func TestMap_GrowTombstones(t *testing.T) {
+	var m intmap.Map[int]
+	m.SetTombstones(true)
+	for k := 1; k <= 1500; k++ {
+		m.Set(k, k)
+	}
+	for k := 2; k <= 1500; k += 2 {
+		m.Delete(k)
+	}
+	// 750 keys and 1000 more fit in 2048 slots, but not with the tombstones.
+	m.Grow(1000)
+	allocs := 0
+	m.SetAllocator(func(n int) []intmap.KeyValue[int] {
+		allocs++
+		return make([]intmap.KeyValue[int], n)
+	}, nil)
+	for k := 1501; k <= 2500; k++ {
+		m.Set(k, k)
+	}
+	if allocs != 0 {
+		t.Errorf("%d rehashes after Grow", allocs)
+	}
+}
+
+func TestMap_SetFreeKey(t *testing.T) {
+	var m intmap.Map[int]
+	for k := -100; k < 100; k++ {
+		m.Set(k, k)
+	}
+	m.SetFreeKey(math.MinInt)
+	if m.FreeKey() != math.MinInt {
+		t.Fatalf("bad free key %d", m.FreeKey())
+	}
+	m.Delete(50)
+	m.Set(math.MinInt, 42)
+	if m.Len() != 200 {
+		t.Fatalf("bad size: expected 200, got %d", m.Len())
+	}
+	for k := -100; k < 100; k++ {
+		if v, ok := m.Get(k); ok != (k != 50) || (ok && v != k) {
+			t.Errorf("bad value for key %d: %d, %v", k, v, ok)
+		}
+	}
+	if v, ok := m.Get(math.MinInt); !ok || v != 42 {
+		t.Errorf("bad value for free key: %d, %v", v, ok)
+	}
+	n := 0
+	for i := m.Iterator(); i.HasNext(); {
+		k, v := i.Next()
+		if k != math.MinInt && k != v {
+			t.Errorf("bad value for key %d: %d", k, v)
+		}
+		n++
+	}
+	if n != m.Len() {
+		t.Errorf("iterated over %d keys, expected %d", n, m.Len())
+	}
+
+	var o intmap.Map[int]
+	o.Set(0, -1)
+	o.Set(math.MinInt, -2)
+	m.Merge(&o, nil)
+	if v, _ := m.Get(0); v != -1 {
+		t.Errorf("bad merged value for key 0: %d", v)
+	}
+	if v, _ := m.Get(math.MinInt); v != -2 {
+		t.Errorf("bad merged value for key %d: %d", math.MinInt, v)
+	}
+	m.Clear()
+	if m.Len() != 0 || m.Has(0) || m.Has(math.MinInt) {
+		t.Error("map not empty after Clear")
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+/*
+Package mph implements a minimal perfect hash function over a set of integer
+keys: a function that maps each of n distinct keys to a distinct index in [0, n).
+Combined with arrays indexed by these indices, it can implement large read-only
+maps, like ID to offset indexes, that take about 5 bits per key on top of the
+values themselves.
+
+The function is built with the BBHash algorithm: keys are hashed into a bit
+array twice as large as the number of keys; positions hit by a single key are
+marked in the array and the colliding keys are handled by the next level, with
+a different hash function and a smaller bit array. The index of a key is the
+rank of its bit in the concatenated levels.
+
+A Func can be serialized with MarshalBinary. Load uses the serialized data in
+place, without copying it, so that a Func stored in a memory mapped file can be
+shared between processes.
+
+Keys that were not in the original set map to arbitrary indices, or to -1.
+Callers that need to detect them must store the keys alongside the values and
+check them.
+*/
+package mph
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"math/rand/v2"
+	"unsafe"
+
+	"github.com/db47h/intmap"
+)
+
+const (
+	gamma     = 2 // bit array size per key
+	maxLevels = 64
+	magic     = "IMPH"
+	version   = 1
+)
+
+// Func is a minimal perfect hash function.
+//
+type Func struct {
+	n      int
+	seed   uint64
+	levels []uint64 // word offsets of the levels in words, plus the total number of words
+	words  []uint64 // level bit arrays
+	ranks  []uint32 // number of bits set in words before each word
+}
+
+// ErrDuplicateKey is returned by New if the key set contains duplicates.
+//
+var ErrDuplicateKey = errors.New("duplicate key")
+
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// pos returns the bit position of key in a level of nbits bits.
+//
+func pos(key int, seed uint64, level int, nbits uint64) uint64 {
+	h := mix64(uint64(key) ^ seed ^ uint64(level)*0x9E3779B97F4A7C15)
+	hi, _ := bits.Mul64(h, nbits)
+	return hi
+}
+
+// New builds a minimal perfect hash function for the given keys, which must be
+// distinct.
+//
+func New(keys []int) (*Func, error) {
+	var seen intmap.Set
+	for _, k := range keys {
+		if seen.Has(k) {
+			return nil, ErrDuplicateKey
+		}
+		seen.Add(k)
+	}
+	for {
+		if f := build(keys, rand.Uint64()); f != nil {
+			return f, nil
+		}
+	}
+}
+
+// build builds the function with the given seed. It returns nil if the keys
+// could not be placed in maxLevels levels.
+//
+func build(keys []int, seed uint64) *Func {
+	f := &Func{n: len(keys), seed: seed, levels: []uint64{0}}
+	rest := append([]int(nil), keys...)
+	for level := 0; len(rest) > 0; level++ {
+		if level == maxLevels {
+			return nil
+		}
+		nw := (gamma*len(rest) + 63) / 64
+		nbits := uint64(nw) * 64
+		hits := make([]uint64, nw)
+		colls := make([]uint64, nw)
+		for _, k := range rest {
+			p := pos(k, seed, level, nbits)
+			if hits[p/64]&(1<<(p%64)) != 0 {
+				colls[p/64] |= 1 << (p % 64)
+			} else {
+				hits[p/64] |= 1 << (p % 64)
+			}
+		}
+		for i := range hits {
+			hits[i] &^= colls[i]
+		}
+		next := rest[:0]
+		for _, k := range rest {
+			if p := pos(k, seed, level, nbits); colls[p/64]&(1<<(p%64)) != 0 {
+				next = append(next, k)
+			}
+		}
+		rest = next
+		f.words = append(f.words, hits...)
+		f.levels = append(f.levels, uint64(len(f.words)))
+	}
+	f.ranks = make([]uint32, len(f.words))
+	r := 0
+	for i, w := range f.words {
+		f.ranks[i] = uint32(r)
+		r += bits.OnesCount64(w)
+	}
+	return f
+}
+
+// Len returns the number of keys the function was built for.
+//
+func (f *Func) Len() int {
+	return f.n
+}
+
+// Index returns the index in [0, f.Len()) of the given key. If the key is not
+// in the set used to build the function, the result is either an arbitrary
+// index or -1.
+//
+func (f *Func) Index(key int) int {
+	for level := 0; level < len(f.levels)-1; level++ {
+		off := f.levels[level]
+		nbits := (f.levels[level+1] - off) * 64
+		p := pos(key, f.seed, level, nbits)
+		w := off + p/64
+		if b := uint64(1) << (p % 64); f.words[w]&b != 0 {
+			return int(f.ranks[w]) + bits.OnesCount64(f.words[w]&(b-1))
+		}
+	}
+	return -1
+}
+
+// Serialized format, all values little endian:
+//
+//	magic     [4]byte
+//	version   uint32
+//	n         uint64
+//	seed      uint64
+//	nlevels   uint64
+//	levels    [nlevels+1]uint64
+//	words     [levels[nlevels]]uint64
+//	ranks     [levels[nlevels]]uint32
+
+const headerSize = 32
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+//
+func (f *Func) MarshalBinary() ([]byte, error) {
+	nw := len(f.words)
+	b := make([]byte, 0, headerSize+8*len(f.levels)+12*nw)
+	b = append(b, magic...)
+	b = binary.LittleEndian.AppendUint32(b, version)
+	b = binary.LittleEndian.AppendUint64(b, uint64(f.n))
+	b = binary.LittleEndian.AppendUint64(b, f.seed)
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(f.levels)-1))
+	for _, l := range f.levels {
+		b = binary.LittleEndian.AppendUint64(b, l)
+	}
+	for _, w := range f.words {
+		b = binary.LittleEndian.AppendUint64(b, w)
+	}
+	for _, r := range f.ranks {
+		b = binary.LittleEndian.AppendUint32(b, r)
+	}
+	return b, nil
+}
+
+var errFormat = errors.New("invalid minimal perfect hash data")
+
+// Load returns the Func serialized in b by MarshalBinary.
+//
+// On little endian platforms and if b is 8 bytes aligned, which is the case
+// for memory mapped files, the returned Func uses b in place and b must not be
+// modified afterwards. Otherwise, the data is copied.
+//
+func Load(b []byte) (*Func, error) {
+	if len(b) < headerSize || string(b[:4]) != magic {
+		return nil, errFormat
+	}
+	if binary.LittleEndian.Uint32(b[4:]) != version {
+		return nil, errors.New("unsupported minimal perfect hash version")
+	}
+	f := &Func{
+		n:    int(binary.LittleEndian.Uint64(b[8:])),
+		seed: binary.LittleEndian.Uint64(b[16:]),
+	}
+	nl := binary.LittleEndian.Uint64(b[24:])
+	b = b[headerSize:]
+	if nl > maxLevels || uint64(len(b)) < 8*(nl+1) {
+		return nil, errFormat
+	}
+	nw := binary.LittleEndian.Uint64(b[8*nl:])
+	if uint64(len(b)) != 8*(nl+1)+12*nw {
+		return nil, errFormat
+	}
+	f.levels = u64s(b[:8*(nl+1)])
+	f.words = u64s(b[8*(nl+1) : 8*(nl+1)+8*nw])
+	f.ranks = u32s(b[8*(nl+1)+8*nw:])
+	if f.levels[0] != 0 || f.n < 0 || uint64(f.n) > 64*nw {
+		return nil, errFormat
+	}
+	// Check that the levels and ranks are those built for n keys, so that
+	// Index never reads out of bounds nor returns an index out of [0, n).
+	rest, r := uint64(f.n), uint64(0)
+	for i := range nl {
+		lo, hi := f.levels[i], f.levels[i+1]
+		if rest == 0 || hi < lo || hi-lo != (gamma*rest+63)/64 {
+			return nil, errFormat
+		}
+		for w := lo; w < hi; w++ {
+			if uint64(f.ranks[w]) != r {
+				return nil, errFormat
+			}
+			r += uint64(bits.OnesCount64(f.words[w]))
+		}
+		if r > uint64(f.n) {
+			return nil, errFormat
+		}
+		rest = uint64(f.n) - r
+	}
+	if rest != 0 {
+		return nil, errFormat
+	}
+	return f, nil
+}
+
+var littleEndian = binary.NativeEndian.Uint16([]byte{1, 0}) == 1
+
+func u64s(b []byte) []uint64 {
+	if len(b) == 0 {
+		return nil
+	}
+	if littleEndian && uintptr(unsafe.Pointer(&b[0]))%8 == 0 {
+		return unsafe.Slice((*uint64)(unsafe.Pointer(&b[0])), len(b)/8)
+	}
+	s := make([]uint64, len(b)/8)
+	for i := range s {
+		s[i] = binary.LittleEndian.Uint64(b[8*i:])
+	}
+	return s
+}
+
+func u32s(b []byte) []uint32 {
+	if len(b) == 0 {
+		return nil
+	}
+	if littleEndian && uintptr(unsafe.Pointer(&b[0]))%4 == 0 {
+		return unsafe.Slice((*uint32)(unsafe.Pointer(&b[0])), len(b)/4)
+	}
+	s := make([]uint32, len(b)/4)
+	for i := range s {
+		s[i] = binary.LittleEndian.Uint32(b[4*i:])
+	}
+	return s
+}
@@ -0,0 +1,102 @@
+package mph_test
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"testing"
+	"unsafe"
+
+	"github.com/db47h/intmap/mph"
+)
+
+func TestFunc(t *testing.T) {
+	r := rand.New(rand.NewSource(424242))
+	// distinct keys of both signs on all architectures
+	keys := make([]int, 0, 100000)
+	set := map[int]bool{0: true}
+	keys = append(keys, 0)
+	for len(keys) < cap(keys) {
+		k := r.Int() - r.Int()
+		if !set[k] {
+			set[k] = true
+			keys = append(keys, k)
+		}
+	}
+	f, err := mph.New(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// copy to an 8 bytes aligned buffer, like a memory mapped file
+	buf := make([]uint64, (len(b)+7)/8)
+	aligned := unsafe.Slice((*byte)(unsafe.Pointer(&buf[0])), len(b))
+	copy(aligned, b)
+	g, err := mph.Load(aligned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make([]bool, len(keys))
+	for _, k := range keys {
+		i := f.Index(k)
+		if i < 0 || i >= len(keys) || seen[i] {
+			t.Fatalf("bad or duplicate index for key %d: %d", k, i)
+		}
+		seen[i] = true
+		if j := g.Index(k); j != i {
+			t.Fatalf("index for key %d: %d after Load, expected %d", k, j, i)
+		}
+	}
+	t.Logf("%.2f bits per key", float64(len(b)*8)/float64(len(keys)))
+
+	if _, err := mph.New([]int{1, 2, 1}); err != mph.ErrDuplicateKey {
+		t.Errorf("New with duplicate keys returned %v", err)
+	}
+	if _, err := mph.Load(b[:len(b)-1]); err == nil {
+		t.Error("Load of truncated data succeeded")
+	}
+}
+
+func TestLoad_Corrupt(t *testing.T) {
+	keys := make([]int, 100)
+	for i := range keys {
+		keys[i] = i * 7919
+	}
+	f, err := mph.New(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for l := range len(b) {
+		if _, err := mph.Load(b[:l]); err == nil {
+			t.Errorf("Load of data truncated to %d bytes succeeded", l)
+		}
+	}
+	// Corrupt data must be rejected, or at least give indices in range.
+	for i := range b {
+		for _, x := range []byte{1, 0x80, 0xff} {
+			c := append([]byte(nil), b...)
+			c[i] ^= x
+			g, err := mph.Load(c)
+			if err != nil {
+				continue
+			}
+			for _, k := range keys {
+				if j := g.Index(k); j < -1 || j >= g.Len() {
+					t.Fatalf("byte %d ^ %#x: index %d for key %d, Len() = %d", i, x, j, k, g.Len())
+				}
+			}
+		}
+	}
+	// an empty level
+	c := append([]byte(nil), b...)
+	binary.LittleEndian.PutUint64(c[32+8:], 0)
+	if _, err := mph.Load(c); err == nil {
+		t.Error("Load of data with an empty level succeeded")
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+import (
+	"math/bits"
+	"math/rand/v2"
+)
+
+// OccupancyMap is an integer keyed map that records which slots are in use in
+// a separate bitmap, one bit per slot, instead of reserving a key to mark free
+// slots. The zero key is therefore a key like any other: there is no separate
+// storage nor extra branch for it in any operation.
+//
+// Iterating over a sparsely populated OccupancyMap is also faster than with
+// Map, since free slots are skipped 64 at a time by scanning the bitmap.
+//
+// OccupancyMap implements the core subset of the Map API. The zero value is an
+// empty map ready to use. An OccupancyMap must not be copied after first use.
+//
+type OccupancyMap[V any] struct {
+	es        []KeyValue[V]
+	used      []uint64 // occupancy bitmap
+	size      int
+	threshold int
+	fillratio float32
+	seed      uint64
+}
+
+// NewOccupancy returns a new OccupancyMap initialized with the given starting
+// capacity and fill ratio.
+//
+// See Map.Init for more details about the capacity and fillratio parameters.
+//
+func NewOccupancy[V any](capacity int, fillratio float32) *OccupancyMap[V] {
+	var m OccupancyMap[V]
+	m.Init(capacity, fillratio)
+	return &m
+}
+
+// Init initializes the map with the given initial capacity and fill ratio. If
+// the map already contains data, it will be reset.
+//
+// See Map.Init for more details about the capacity and fillratio parameters.
+//
+func (m *OccupancyMap[V]) Init(capacity int, fillratio float32) {
+	capacity = nextPowerOf2(capacity)
+	if capacity < 0 {
+		panic("invalid capacity requested")
+	}
+	if capacity < 2 {
+		capacity = 2
+	}
+	m.seed = rand.Uint64()
+	m.fillratio = fillratio
+	m.alloc(capacity)
+}
+
+// alloc allocates a new backing array of l slots, and picks a random seed if
+// the map had none.
+//
+func (m *OccupancyMap[V]) alloc(l int) {
+	if len(m.es) == 0 {
+		m.seed = rand.Uint64()
+	}
+	m.es = make([]KeyValue[V], l)
+	m.used = make([]uint64, (l+63)/64)
+	m.size = 0
+	m.threshold = thresholdFor(l, m.fillratio)
+}
+
+func (m *OccupancyMap[V]) isUsed(idx int) bool {
+	return m.used[idx/64]&(1<<(idx%64)) != 0
+}
+
+// Set sets the value for the given key.
+//
+func (m *OccupancyMap[V]) Set(key int, value V) {
+	if len(m.es) == 0 {
+		m.fillratio = defaultFillRatio
+		m.alloc(8)
+	}
+	idx, found := m.probe(key)
+	if found {
+		m.es[idx].Value = value
+		return
+	}
+	if m.size >= m.threshold {
+		m.resize(len(m.es) * 2)
+		idx, _ = m.probe(key)
+	}
+	m.es[idx] = KeyValue[V]{key, value}
+	m.used[idx/64] |= 1 << (idx % 64)
+	m.size++
+}
+
+// Get returns the value associated with the given key and true, or the zero
+// value for V and false if the key is not set.
+//
+func (m *OccupancyMap[V]) Get(key int) (v V, ok bool) {
+	if idx, found := m.probe(key); found {
+		return m.es[idx].Value, true
+	}
+	return v, false
+}
+
+// Has returns true if the given key is set.
+//
+func (m *OccupancyMap[V]) Has(key int) bool {
+	_, found := m.probe(key)
+	return found
+}
+
+// Delete deletes the given key and returns true if it was set.
+//
+func (m *OccupancyMap[V]) Delete(key int) bool {
+	idx, found := m.probe(key)
+	if !found {
+		return false
+	}
+	m.shiftKeys(idx)
+	m.size--
+	return true
+}
+
+// Clear removes all keys from the map. It keeps the allocated backing arrays.
+//
+func (m *OccupancyMap[V]) Clear() {
+	clear(m.es)
+	clear(m.used)
+	m.size = 0
+}
+
+// Len returns the number if keys set in the map.
+//
+func (m *OccupancyMap[V]) Len() int {
+	return m.size
+}
+
+// Cap returns the capacity of the map, that is the number of slots in its
+// backing array.
+//
+func (m *OccupancyMap[V]) Cap() int {
+	return len(m.es)
+}
+
+// Keys returns an unordered slice of the map keys.
+//
+func (m *OccupancyMap[V]) Keys() []int {
+	ks := make([]int, 0, m.size)
+	m.Range(func(k int, _ V) bool {
+		ks = append(ks, k)
+		return true
+	})
+	return ks
+}
+
+// Range calls f sequentially for each key and value present in the map. If f
+// returns false, Range stops the iteration. f must not modify the map.
+//
+func (m *OccupancyMap[V]) Range(f func(key int, value V) bool) {
+	for w, word := range m.used {
+		for word != 0 {
+			i := w*64 + bits.TrailingZeros64(word)
+			word &= word - 1
+			if !f(m.es[i].Key, m.es[i].Value) {
+				return
+			}
+		}
+	}
+}
+
+// hash returns the seeded hash of key.
+//
+func (m *OccupancyMap[V]) hash(key int) int {
+	return int(mix64(uint64(key) ^ m.seed))
+}
+
+func (m *OccupancyMap[V]) probe(key int) (idx int, found bool) {
+	mod := len(m.es) - 1
+	if mod < 0 {
+		return -1, false
+	}
+	idx = m.hash(key) & mod
+	for m.isUsed(idx) {
+		if m.es[idx].Key == key {
+			return idx, true
+		}
+		idx = nextIdx(idx) & mod
+	}
+	return idx, false
+}
+
+func (m *OccupancyMap[V]) resize(l int) {
+	es, used := m.es, m.used
+	m.alloc(l)
+	mod := l - 1
+	for w, word := range used {
+		for word != 0 {
+			i := w*64 + bits.TrailingZeros64(word)
+			word &= word - 1
+			idx := m.hash(es[i].Key) & mod
+			for m.isUsed(idx) {
+				idx = nextIdx(idx) & mod
+			}
+			m.es[idx] = es[i]
+			m.used[idx/64] |= 1 << (idx % 64)
+			m.size++
+		}
+	}
+}
+
+func (m *OccupancyMap[V]) shiftKeys(idx int) {
+	mod := len(m.es) - 1
+	last := backShift(idx, mod, func(i int) int {
+		if m.isUsed(i) {
+			return m.hash(m.es[i].Key) & mod
+		}
+		return -1
+	}, func(dst, src int) {
+		m.es[dst] = m.es[src]
+	})
+	m.es[last] = KeyValue[V]{}
+	m.used[last/64] &^= 1 << (last % 64)
+}
@@ -0,0 +1,18 @@
+package intmap_test
+
+import (
+	"testing"
+
+	"github.com/db47h/intmap"
+)
+
+func TestOccupancyMap_Seed(t *testing.T) {
+	var a, b intmap.OccupancyMap[int]
+	for k := 1; k <= 100; k++ {
+		a.Set(k, k)
+		b.Set(k, k)
+	}
+	if sa, sb := intmap.OccupancySeed(&a), intmap.OccupancySeed(&b); sa == sb || sa == 0 {
+		t.Errorf("zero value maps got seeds %#x and %#x", sa, sb)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+/*
+Package ordered implements an integer keyed map that preserves insertion order.
+
+Keys are mapped to their position in a dense array of entries by an
+intmap.Map[int]. Deleting a key leaves a hole in the entry array. Holes are
+reclaimed when setting keys, once they make up more than half of it, unless an
+iteration is in progress.
+*/
+package ordered
+
+import (
+	"iter"
+
+	"github.com/db47h/intmap"
+)
+
+type entry[V any] struct {
+	key   int
+	value V
+	live  bool
+}
+
+// Map is an int keyed map that iterates over its keys in insertion order.
+// Setting the value of an existing key does not change its position.
+//
+// The zero value of a Map is an empty map ready to use. A Map must not be
+// copied after first use.
+//
+type Map[V any] struct {
+	idx   intmap.Map[int]
+	es    []entry[V]
+	holes int
+	iters int // number of iterations in progress, which disable compaction
+}
+
+// New returns a new Map initialized with the given starting capacity.
+//
+func New[V any](capacity int) *Map[V] {
+	var m Map[V]
+	m.idx.Init(capacity, 0.875)
+	m.es = make([]entry[V], 0, capacity)
+	return &m
+}
+
+// Set sets or resets the value for the given key.
+//
+func (m *Map[V]) Set(key int, value V) {
+	if m.holes > len(m.es)/2 && m.iters == 0 {
+		m.compact()
+	}
+	i := m.idx.Entry(key).OrInsert(len(m.es))
+	if *i == len(m.es) {
+		m.es = append(m.es, entry[V]{key, value, true})
+		return
+	}
+	m.es[*i].value = value
+}
+
+// Get returns the value associated with the given key and ok set to true if
+// the key exists. If the keys does not exist, it returns the zero value for the
+// Value type and false.
+//
+func (m *Map[V]) Get(key int) (v V, ok bool) {
+	i, ok := m.idx.Get(key)
+	if !ok {
+		return v, false
+	}
+	return m.es[i].value, true
+}
+
+// Delete deletes the given key and returns true if the key was present in the
+// map.
+//
+func (m *Map[V]) Delete(key int) bool {
+	i, ok := m.idx.GetAndDelete(key)
+	if !ok {
+		return false
+	}
+	m.es[i] = entry[V]{}
+	m.holes++
+	return true
+}
+
+func (m *Map[V]) compact() {
+	n := 0
+	for _, e := range m.es {
+		if e.live {
+			m.es[n] = e
+			m.idx.Set(e.key, n)
+			n++
+		}
+	}
+	clear(m.es[n:])
+	m.es = m.es[:n]
+	m.holes = 0
+}
+
+// Len returns the number if keys set in the map.
+//
+func (m *Map[V]) Len() int {
+	return len(m.es) - m.holes
+}
+
+// All returns an iterator over the map's key/value pairs in insertion order.
+//
+// While iterating, changing the value of any existing key is supported, as
+// well as deleting any key. Inserting new keys is not supported.
+//
+func (m *Map[V]) All() iter.Seq2[int, V] {
+	return func(yield func(int, V) bool) {
+		m.iters++
+		defer func() { m.iters-- }()
+		for i := 0; i < len(m.es); i++ {
+			if e := &m.es[i]; e.live && !yield(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns a slice of the map keys in insertion order.
+//
+func (m *Map[V]) Keys() []int {
+	ks := make([]int, 0, m.Len())
+	for _, e := range m.es {
+		if e.live {
+			ks = append(ks, e.key)
+		}
+	}
+	return ks
+}
@@ -0,0 +1,70 @@
+package ordered_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/db47h/intmap/ordered"
+)
+
+func TestMap(t *testing.T) {
+	var m ordered.Map[int]
+	var exp []int
+	for k := 100; k > 0; k-- {
+		m.Set(k, k)
+		exp = append(exp, k)
+	}
+	m.Set(0, 0)
+	m.Set(99, -99)
+	exp = append(exp, 0)
+	for k, v := range m.All() {
+		if k%3 != 0 {
+			m.Delete(k)
+		} else if v != k && k != 99 {
+			t.Errorf("bad value for key %d: %d", k, v)
+		}
+	}
+	exp = slices.DeleteFunc(exp, func(k int) bool { return k%3 != 0 })
+	for k := 200; k < 300; k++ {
+		m.Set(k, k)
+		exp = append(exp, k)
+	}
+	if ks := m.Keys(); !slices.Equal(ks, exp) {
+		t.Errorf("bad key order: expected %v, got %v", exp, ks)
+	}
+	if v, _ := m.Get(99); v != -99 {
+		t.Errorf("bad value for key 99: %d", v)
+	}
+	if m.Len() != len(exp) {
+		t.Errorf("bad size: expected %d, got %d", len(exp), m.Len())
+	}
+}
+
+func TestMap_AllMutate(t *testing.T) {
+	var m ordered.Map[int]
+	for k := 0; k < 8; k++ {
+		m.Set(k, k)
+	}
+	var seen []int
+	for k := range m.All() {
+		seen = append(seen, k)
+		if k == 0 {
+			for d := 2; d < 7; d++ {
+				m.Delete(d)
+			}
+			// enough holes to compact, which must wait for the iteration
+			m.Set(0, 100)
+			m.Set(7, 700)
+		}
+	}
+	if !slices.Equal(seen, []int{0, 1, 7}) {
+		t.Errorf("All visited %v, expected [0 1 7]", seen)
+	}
+	m.Set(1, 10)
+	if ks := m.Keys(); !slices.Equal(ks, []int{0, 1, 7}) {
+		t.Errorf("Keys() = %v, expected [0 1 7]", ks)
+	}
+	if v, _ := m.Get(7); v != 700 {
+		t.Errorf("bad value for key 7: %d", v)
+	}
+}
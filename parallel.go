@@ -0,0 +1,153 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+import (
+	"runtime"
+	"sync"
+)
+
+// ParallelRange calls fn for each key and value present in the map, using up
+// to workers goroutines. If workers <= 0, runtime.GOMAXPROCS(0) goroutines are
+// used. ParallelRange returns once all calls to fn have returned.
+//
+// The backing array is split in contiguous chunks, each processed by a
+// different goroutine. fn must therefore be safe for concurrent use, and the
+// map must not be modified until ParallelRange returns.
+//
+func (m *Map[V]) ParallelRange(workers int, fn func(key int, value V)) {
+	if m.hasFreeKey {
+		fn(m.free, m.freeKeyValue)
+	}
+	forChunks(len(m.es), workers, func(_, lo, hi int) {
+		es := m.es[lo:hi]
+		for i := range es {
+			if k := es[i].Key; k != m.free {
+				fn(k, es[i].Value)
+			}
+		}
+	})
+}
+
+// numChunks returns the number of chunks forChunks will split [0, n) into.
+//
+func numChunks(n, workers int) int {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > n {
+		workers = n
+	}
+	return workers
+}
+
+// forChunks splits [0, n) into numChunks(n, workers) contiguous chunks and
+// calls fn(w, lo, hi) for each chunk w in a separate goroutine. It returns once
+// all calls to fn have returned.
+//
+func forChunks(n, workers int, fn func(w, lo, hi int)) {
+	workers = numChunks(n, workers)
+	if workers == 1 {
+		fn(0, 0, n)
+		return
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func(w, lo, hi int) {
+			defer wg.Done()
+			fn(w, lo, hi)
+		}(w, n*w/workers, n*(w+1)/workers)
+	}
+	wg.Wait()
+}
+
+// Split divides the map into n disjoint maps, each holding the key-value pairs
+// of a contiguous chunk of the backing array. The original map is left
+// unchanged. If n <= 0, it is set to 1.
+//
+// The resulting maps have the default fill ratio and can for example be
+// processed in parallel and later combined with Merge.
+//
+func (m *Map[V]) Split(n int) []*Map[V] {
+	if n <= 0 {
+		n = 1
+	}
+	ms := make([]*Map[V], n)
+	l := len(m.es)
+	for w := range ms {
+		p := new(Map[V])
+		p.free = m.free
+		p.deterministic = m.deterministic
+		p.fibonacci = m.fibonacci
+		p.triangular = m.triangular
+		p.tombstones = m.tombstones
+		es := m.es[l*w/n : l*(w+1)/n]
+		cnt := 0
+		for i := range es {
+			if es[i].Key != m.free {
+				cnt++
+			}
+		}
+		p.grow(cnt)
+		for i := range es {
+			if k := es[i].Key; k != m.free {
+				p.Set(k, es[i].Value)
+			}
+		}
+		ms[w] = p
+	}
+	if m.hasFreeKey {
+		ms[0].Set(m.free, m.freeKeyValue)
+	}
+	return ms
+}
+
+// MapReduce calls mapFn for each key and value present in the map and combines
+// the results with reduceFn, using up to workers goroutines. If workers <= 0,
+// runtime.GOMAXPROCS(0) goroutines are used. If the map is empty, MapReduce
+// returns the zero value for R.
+//
+// The backing array is split in contiguous chunks, each reduced by a different
+// goroutine, and the partial results are then reduced in chunk order. mapFn and
+// reduceFn must therefore be safe for concurrent use, and reduceFn should be
+// associative. The map must not be modified until MapReduce returns.
+//
+func MapReduce[V, R any](m *Map[V], mapFn func(key int, value V) R, reduceFn func(a, b R) R, workers int) R {
+	type partial struct {
+		r  R
+		ok bool
+	}
+	ps := make([]partial, numChunks(len(m.es), workers))
+	forChunks(len(m.es), workers, func(w, lo, hi int) {
+		p := &ps[w]
+		es := m.es[lo:hi]
+		for i := range es {
+			k := es[i].Key
+			if k == m.free {
+				continue
+			}
+			r := mapFn(k, es[i].Value)
+			if p.ok {
+				r = reduceFn(p.r, r)
+			}
+			p.r, p.ok = r, true
+		}
+	})
+	var res partial
+	if m.hasFreeKey {
+		res = partial{mapFn(m.free, m.freeKeyValue), true}
+	}
+	for _, p := range ps {
+		switch {
+		case !p.ok:
+		case res.ok:
+			res.r = reduceFn(res.r, p.r)
+		default:
+			res = p
+		}
+	}
+	return res.r
+}
@@ -0,0 +1,62 @@
+package intmap_test
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/db47h/intmap"
+)
+
+func TestMap_ParallelRange(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 10000; k++ {
+		m.Set(k, k)
+	}
+	for _, w := range []int{0, 1, 3, 100000} {
+		var sum, n int64
+		m.ParallelRange(w, func(k, v int) {
+			atomic.AddInt64(&sum, int64(v))
+			atomic.AddInt64(&n, 1)
+		})
+		if n != 10000 || sum != 49995000 {
+			t.Errorf("workers=%d: bad count or sum: %d, %d", w, n, sum)
+		}
+	}
+}
+
+func TestMap_Split(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 1000; k++ {
+		m.Set(k, k)
+	}
+	parts := m.Split(7)
+	if len(parts) != 7 {
+		t.Fatalf("bad number of parts: expected 7, got %d", len(parts))
+	}
+	var r intmap.Map[int]
+	n := 0
+	for _, p := range parts {
+		n += p.Len()
+		r.Merge(p, nil)
+	}
+	if n != 1000 || r.Len() != 1000 {
+		t.Errorf("bad sizes: %d total, %d merged", n, r.Len())
+	}
+}
+
+func TestMapReduce(t *testing.T) {
+	var m intmap.Map[int]
+	add := func(a, b int) int { return a + b }
+	sq := func(k, v int) int { return v * v }
+	if r := intmap.MapReduce(&m, sq, add, 4); r != 0 {
+		t.Errorf("MapReduce() = %d on empty map", r)
+	}
+	for k := 0; k < 1000; k++ {
+		m.Set(k, k)
+	}
+	for _, w := range []int{0, 1, 4, 10000} {
+		if r := intmap.MapReduce(&m, sq, add, w); r != 332833500 {
+			t.Errorf("workers=%d: MapReduce() = %d; expected 332833500", w, r)
+		}
+	}
+}
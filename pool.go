@@ -0,0 +1,57 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+import (
+	"math/bits"
+	"sync"
+	"unsafe"
+)
+
+// TablePool is a pool of backing arrays, sorted in size classes of powers of
+// two. It is meant to be used as the allocator of maps that are frequently
+// grown, initialized or discarded, so that tables released by one map are
+// reused by the next one instead of being left to the garbage collector:
+//
+//	var pool intmap.TablePool[int]
+//
+//	m := new(intmap.Map[int])
+//	m.SetAllocator(pool.Alloc, pool.Release)
+//
+// A single TablePool can be shared by any number of maps and is safe for
+// concurrent use. Like with sync.Pool, pooled tables may be reclaimed by the
+// garbage collector at any time.
+//
+// The zero value is an empty pool ready to use.
+//
+type TablePool[V any] struct {
+	classes [bits.UintSize]sync.Pool // pointers to the first entry of tables of length 1<<i
+}
+
+// Alloc returns a zeroed backing array of length n, reusing a pooled one if
+// available.
+//
+func (p *TablePool[V]) Alloc(n int) []KeyValue[V] {
+	if n <= 0 || n&(n-1) != 0 {
+		return make([]KeyValue[V], n)
+	}
+	if e, _ := p.classes[bits.TrailingZeros(uint(n))].Get().(*KeyValue[V]); e != nil {
+		es := unsafe.Slice(e, n)
+		clear(es)
+		return es
+	}
+	return make([]KeyValue[V], n)
+}
+
+// Release returns a backing array to the pool. es must not be used afterwards.
+// Arrays whose length is not a power of two are left to the garbage collector.
+//
+func (p *TablePool[V]) Release(es []KeyValue[V]) {
+	n := len(es)
+	if n == 0 || n&(n-1) != 0 {
+		return
+	}
+	p.classes[bits.TrailingZeros(uint(n))].Put(&es[0])
+}
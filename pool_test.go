@@ -0,0 +1,39 @@
+package intmap_test
+
+import (
+	"testing"
+
+	"github.com/db47h/intmap"
+)
+
+func TestTablePool(t *testing.T) {
+	var (
+		pool intmap.TablePool[int]
+		m    intmap.Map[int]
+	)
+	m.SetAllocator(pool.Alloc, pool.Release)
+	for i := 0; i < 3; i++ {
+		for k := 0; k < 1000; k++ {
+			m.Set(k, k)
+		}
+		for k := 0; k < 1000; k++ {
+			if v, _ := m.Get(k); v != k {
+				t.Fatalf("bad value for key %d: %d", k, v)
+			}
+		}
+		m.Init(0, 0.875)
+		if m.Len() != 0 {
+			t.Fatalf("Len() = %d after Init", m.Len())
+		}
+	}
+	es := pool.Alloc(64)
+	if len(es) != 64 {
+		t.Fatalf("bad length: %d", len(es))
+	}
+	for i := range es {
+		if es[i] != (intmap.KeyValue[int]{}) {
+			t.Fatal("pooled array not cleared")
+		}
+	}
+	pool.Release(es)
+}
@@ -0,0 +1,15 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build amd64 && !purego
+
+package intmap
+
+import "unsafe"
+
+// prefetch hints the CPU to load the cache line at p into all cache levels.
+// It never faults, even if p is not a valid address.
+//
+//go:noescape
+func prefetch(p unsafe.Pointer)
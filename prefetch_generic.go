@@ -0,0 +1,14 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build !amd64 || purego
+
+package intmap
+
+import "unsafe"
+
+// prefetch is a no-op on platforms without a prefetch instruction available
+// from Go assembly.
+//
+func prefetch(p unsafe.Pointer) {}
@@ -0,0 +1,40 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+import "unsafe"
+
+// PtrMap is a map keyed by pointer identity, for interning or object registry
+// use cases. Builtin maps keyed by interfaces need to hash and compare the
+// dynamic type along with the pointer, and maps keyed by a concrete pointer
+// type need one instantiation per type; a PtrMap only looks at the address.
+//
+// Keys are stored as unsafe.Pointer and are therefore visible to the garbage
+// collector: an object stays alive as long as it is a key in a PtrMap. Callers
+// that need weak references should convert addresses to uintptr and use a
+// MapOf[uintptr, V] instead, at their own risk.
+//
+// Since addresses are aligned, their low bits carry little information. The
+// hash function mixes all bits of an address, so that objects of the same size
+// class do not all end up in the same few slots.
+//
+// PtrMap implements the core subset of the Map API, with the nil pointer
+// playing the role of the zero key. The zero value is an empty map ready to
+// use. A PtrMap must not be copied after first use.
+//
+type PtrMap[V any] struct {
+	table[unsafe.Pointer, V]
+}
+
+// NewPtr returns a new PtrMap initialized with the given starting capacity and
+// fill ratio.
+//
+// See Map.Init for more details about the capacity and fillratio parameters.
+//
+func NewPtr[V any](capacity int, fillratio float32) *PtrMap[V] {
+	var m PtrMap[V]
+	m.Init(capacity, fillratio)
+	return &m
+}
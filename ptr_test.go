@@ -0,0 +1,37 @@
+package intmap_test
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/db47h/intmap"
+)
+
+func TestPtrMap_Objects(t *testing.T) {
+	type object struct{ id int }
+	var m intmap.PtrMap[int]
+	objs := make([]*object, 1000)
+	for i := range objs {
+		objs[i] = &object{i}
+		m.Set(unsafe.Pointer(objs[i]), i)
+	}
+	m.Set(nil, 42)
+	if m.Len() != len(objs)+1 {
+		t.Fatalf("Len() = %d, expected %d", m.Len(), len(objs)+1)
+	}
+	for i, o := range objs {
+		if v, ok := m.Get(unsafe.Pointer(o)); !ok || v != o.id {
+			t.Errorf("bad value for object %d: %d, %v", i, v, ok)
+		}
+	}
+	if v, ok := m.Get(nil); !ok || v != 42 {
+		t.Errorf("bad value for nil key: %d, %v", v, ok)
+	}
+	if m.Has(unsafe.Pointer(&object{})) {
+		t.Error("Has returned true for a new object")
+	}
+	m.Clear()
+	if m.Len() != 0 || m.Has(nil) || m.Has(unsafe.Pointer(objs[1])) {
+		t.Error("map not empty after Clear")
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+import "math/rand"
+
+// RandomKey returns a key picked uniformly at random from the map using r as a
+// source of randomness, and ok set to true. If the map is empty, it returns 0
+// and false.
+//
+func (m *Map[V]) RandomKey(r *rand.Rand) (key int, ok bool) {
+	n := m.Len()
+	if n == 0 {
+		return 0, false
+	}
+	l := len(m.es)
+	if n*randomKeyMaxTries < l {
+		// Maps are not shrunk after deletions: rejection sampling would take
+		// too many tries in a mostly empty backing array.
+		kv, _ := m.Nth(r.Intn(n))
+		return kv.Key, true
+	}
+	// Slot l stands for the free key. With at least one used slot out of
+	// randomKeyMaxTries, rejection sampling converges fast.
+	for {
+		i := r.Intn(l + 1)
+		if i == l {
+			if m.hasFreeKey {
+				return m.free, true
+			}
+			continue
+		}
+		if k := m.es[i].Key; k != m.free {
+			return k, true
+		}
+	}
+}
+
+// randomKeyMaxTries is the expected number of tries above which RandomKey
+// picks a key by position instead of by rejection sampling.
+//
+const randomKeyMaxTries = 16
+
+// Sample returns up to n distinct key-value pairs picked uniformly at random
+// from the map using r as a source of randomness. If n is greater than or equal
+// to m.Len(), all key-value pairs are returned.
+//
+func (m *Map[V]) Sample(r *rand.Rand, n int) []KeyValue[V] {
+	if n <= 0 {
+		return nil
+	}
+	if l := m.Len(); n > l {
+		n = l
+	}
+	kvs := make([]KeyValue[V], 0, n)
+	i := 0
+	m.Range(func(k int, v V) bool {
+		// reservoir sampling
+		if i < n {
+			kvs = append(kvs, KeyValue[V]{k, v})
+		} else if j := r.Intn(i + 1); j < n {
+			kvs[j] = KeyValue[V]{k, v}
+		}
+		i++
+		return true
+	})
+	return kvs
+}
@@ -0,0 +1,85 @@
+package intmap_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/db47h/intmap"
+)
+
+func TestMap_Sample(t *testing.T) {
+	var m intmap.Map[int]
+	r := rand.New(rand.NewSource(424242))
+	if _, ok := m.RandomKey(r); ok {
+		t.Error("RandomKey succeeded on empty map")
+	}
+	for k := 0; k < 10; k++ {
+		m.Set(k, -k)
+	}
+	hits := make(map[int]int)
+	for i := 0; i < 10000; i++ {
+		k, ok := m.RandomKey(r)
+		if !ok || !m.Has(k) {
+			t.Fatalf("RandomKey() = %d, %v", k, ok)
+		}
+		hits[k]++
+	}
+	for k := 0; k < 10; k++ {
+		if hits[k] < 800 || hits[k] > 1200 {
+			t.Errorf("key %d picked %d times out of 10000", k, hits[k])
+		}
+	}
+	kvs := m.Sample(r, 5)
+	seen := make(map[int]bool)
+	for _, kv := range kvs {
+		if kv.Value != -kv.Key || seen[kv.Key] {
+			t.Errorf("bad sample entry %v", kv)
+		}
+		seen[kv.Key] = true
+	}
+	if len(kvs) != 5 {
+		t.Errorf("bad sample size: expected 5, got %d", len(kvs))
+	}
+	if l := len(m.Sample(r, 20)); l != 10 {
+		t.Errorf("bad sample size: expected 10, got %d", l)
+	}
+}
+
+// countingSource counts the random numbers drawn from it.
+type countingSource struct {
+	rand.Source
+	n int
+}
+
+func (s *countingSource) Int63() int64 {
+	s.n++
+	return s.Source.Int63()
+}
+
+func TestMap_RandomKeySparse(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 0; k < 1<<16; k++ {
+		m.Set(k, k)
+	}
+	for k := 3; k < 1<<16; k++ {
+		m.Delete(k)
+	}
+	src := &countingSource{Source: rand.NewSource(424242)}
+	r := rand.New(src)
+	hits := make(map[int]int)
+	for i := 0; i < 3000; i++ {
+		k, ok := m.RandomKey(r)
+		if !ok || !m.Has(k) {
+			t.Fatalf("RandomKey() = %d, %v", k, ok)
+		}
+		hits[k]++
+	}
+	if src.n > 3*3000 {
+		t.Errorf("%d random numbers drawn for 3000 keys", src.n)
+	}
+	for k := 0; k < 3; k++ {
+		if hits[k] < 800 || hits[k] > 1200 {
+			t.Errorf("key %d picked %d times out of 3000", k, hits[k])
+		}
+	}
+}
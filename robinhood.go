@@ -0,0 +1,222 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+import "math/rand/v2"
+
+type rhEntry[V any] struct {
+	key   int
+	value V
+	psl   int // probe sequence length + 1, 0 for free slots
+}
+
+// RobinHoodMap is an integer keyed map using Robin Hood hashing: when inserting
+// a key, entries that are closer to their home slot than the key being inserted
+// give up their slot to it. This evens out probe sequence lengths, so that the
+// map performs well at fill ratios as high as 95%.
+//
+// Since entries along a probe sequence are sorted by decreasing displacement,
+// lookups of missing keys stop as soon as they reach an entry closer to its
+// home slot than the key would be, and never probe further than the longest
+// probe sequence in the map. Deletions shift back the following entries, so
+// there are no tombstones. The zero key needs no special handling.
+//
+// The zero value is an empty map ready to use, with a fill ratio of 87.5%. A
+// RobinHoodMap must not be copied after first use.
+//
+type RobinHoodMap[V any] struct {
+	es        []rhEntry[V]
+	size      int
+	threshold int
+	maxPSL    int // longest probe sequence length + 1 since the last rehash
+	seed      uint
+}
+
+// NewRobinHood returns a new RobinHoodMap initialized with the given starting
+// capacity and fill ratio.
+//
+// See Map.Init for more details about the capacity and fillratio parameters.
+//
+func NewRobinHood[V any](capacity int, fillratio float32) *RobinHoodMap[V] {
+	var m RobinHoodMap[V]
+	m.Init(capacity, fillratio)
+	return &m
+}
+
+// Init initializes the map with the given initial capacity and fill ratio. If
+// the map already contains data, it will be reset.
+//
+// See Map.Init for more details about the capacity and fillratio parameters.
+//
+func (m *RobinHoodMap[V]) Init(capacity int, fillratio float32) {
+	capacity = nextPowerOf2(capacity)
+	if capacity < 0 {
+		panic("invalid capacity requested")
+	}
+	if capacity < 2 {
+		capacity = 2
+	}
+	m.es = make([]rhEntry[V], capacity)
+	m.size = 0
+	m.maxPSL = 0
+	m.threshold = thresholdFor(capacity, fillratio)
+	m.seed = uint(rand.Uint64())
+}
+
+func (m *RobinHoodMap[V]) home(key int) int {
+	return int(mix64(uint64(key)^uint64(m.seed))) & (len(m.es) - 1)
+}
+
+// MaxProbe returns the length of the longest probe sequence since the last
+// rehash, that is the maximum number of slots a lookup may have to look at.
+//
+func (m *RobinHoodMap[V]) MaxProbe() int {
+	return m.maxPSL
+}
+
+// find returns the index of the slot holding key, or -1 if there is none.
+//
+func (m *RobinHoodMap[V]) find(key int) int {
+	if len(m.es) == 0 {
+		return -1
+	}
+	mod := len(m.es) - 1
+	idx := m.home(key)
+	for psl := 1; psl <= m.maxPSL; psl++ {
+		e := &m.es[idx]
+		if e.psl < psl {
+			// free slot, or entry closer to its home slot than key would be
+			return -1
+		}
+		if e.key == key {
+			return idx
+		}
+		idx = nextIdx(idx) & mod
+	}
+	return -1
+}
+
+// Set sets the value for the given key.
+//
+func (m *RobinHoodMap[V]) Set(key int, value V) {
+	if i := m.find(key); i >= 0 {
+		m.es[i].value = value
+		return
+	}
+	if m.size >= m.threshold {
+		if len(m.es) == 0 {
+			m.Init(8, defaultFillRatio)
+		} else {
+			m.resize(len(m.es) << 1)
+		}
+	}
+	m.insert(rhEntry[V]{key, value, 1})
+}
+
+// insert inserts e, whose key must not be in the map.
+//
+func (m *RobinHoodMap[V]) insert(e rhEntry[V]) {
+	mod := len(m.es) - 1
+	idx := m.home(e.key)
+	for {
+		t := &m.es[idx]
+		if t.psl == 0 {
+			*t = e
+			break
+		}
+		if t.psl < e.psl {
+			// take from the rich
+			if e.psl > m.maxPSL {
+				m.maxPSL = e.psl
+			}
+			*t, e = e, *t
+		}
+		e.psl++
+		idx = nextIdx(idx) & mod
+	}
+	if e.psl > m.maxPSL {
+		m.maxPSL = e.psl
+	}
+	m.size++
+}
+
+func (m *RobinHoodMap[V]) resize(l int) {
+	if l < 0 {
+		panic("map size overflows addressable space")
+	}
+	es := m.es
+	m.es = make([]rhEntry[V], l)
+	m.size = 0
+	m.maxPSL = 0
+	m.threshold <<= 1
+	for i := range es {
+		if es[i].psl != 0 {
+			m.insert(rhEntry[V]{es[i].key, es[i].value, 1})
+		}
+	}
+}
+
+// Get returns the value associated with the given key and true, or the zero
+// value for V and false if the key is not set.
+//
+func (m *RobinHoodMap[V]) Get(key int) (v V, ok bool) {
+	if i := m.find(key); i >= 0 {
+		return m.es[i].value, true
+	}
+	return v, false
+}
+
+// Has returns true if the given key is set.
+//
+func (m *RobinHoodMap[V]) Has(key int) bool {
+	return m.find(key) >= 0
+}
+
+// Delete deletes the given key and returns true if it was set.
+//
+func (m *RobinHoodMap[V]) Delete(key int) bool {
+	idx := m.find(key)
+	if idx < 0 {
+		return false
+	}
+	mod := len(m.es) - 1
+	for {
+		next := nextIdx(idx) & mod
+		if m.es[next].psl <= 1 {
+			break
+		}
+		m.es[idx] = m.es[next]
+		m.es[idx].psl--
+		idx = next
+	}
+	m.es[idx] = rhEntry[V]{}
+	m.size--
+	return true
+}
+
+// Clear removes all keys from the map. It keeps the allocated storage.
+//
+func (m *RobinHoodMap[V]) Clear() {
+	clear(m.es)
+	m.size = 0
+	m.maxPSL = 0
+}
+
+// Len returns the number if keys set in the map.
+//
+func (m *RobinHoodMap[V]) Len() int {
+	return m.size
+}
+
+// Range calls f sequentially for each key and value present in the map. If f
+// returns false, Range stops the iteration. f must not modify the map.
+//
+func (m *RobinHoodMap[V]) Range(f func(key int, value V) bool) {
+	for i := range m.es {
+		if e := &m.es[i]; e.psl != 0 && !f(e.key, e.value) {
+			return
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package intmap_test
+
+import (
+	"testing"
+
+	"github.com/db47h/intmap"
+)
+
+func TestRobinHoodMap_MaxProbe(t *testing.T) {
+	m := intmap.NewRobinHood[int](16, 0.95)
+	for k := 1; k <= 1000; k++ {
+		m.Set(k, k)
+	}
+	if m.MaxProbe() <= 0 || m.MaxProbe() > m.Len() {
+		t.Errorf("bad MaxProbe: %d", m.MaxProbe())
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+import "math/rand/v2"
+
+const (
+	segMaxCap   = 1 << 12 // capacity above which segments are split instead of grown
+	segMaxDepth = 32      // maximum number of hash bits used to select a segment
+)
+
+// segment is a part of a SegmentedMap holding the keys whose hash starts with
+// the same depth bits.
+//
+type segment[V any] struct {
+	m     Map[V]
+	depth uint
+}
+
+// SegmentedMap is an integer keyed map that bounds the extra memory needed to
+// grow. Keys are spread over a directory of segments, each being a Map with a
+// capacity of at most 4096 slots, using the high bits of a seeded hash of the
+// key (extendible hashing). When a full segment would have to grow past that
+// capacity, it is split in two, and only the keys of that segment are
+// rehashed.
+//
+// Growing a Map requires its old and new backing arrays at the same time, that
+// is three times its steady-state memory for a brief period. Growing a
+// SegmentedMap only ever requires three segments worth of extra memory,
+// regardless of its size. Lookups are slightly slower since they go through the
+// directory first. Segments are never merged back after deletions.
+//
+// SegmentedMap implements the core subset of the Map API. The zero value is an
+// empty map ready to use. A SegmentedMap must not be copied after first use.
+//
+type SegmentedMap[V any] struct {
+	dir   []*segment[V]
+	depth uint // number of hash bits used to index dir
+	seed  uint64
+	n     int
+}
+
+// index returns the directory index of key.
+//
+func (m *SegmentedMap[V]) index(key int) int {
+	return int(mix64(uint64(key)^m.seed) >> 1 >> (63 - m.depth))
+}
+
+func (m *SegmentedMap[V]) segment(key int) *segment[V] {
+	if m.dir == nil {
+		m.seed = rand.Uint64()
+		m.dir = []*segment[V]{new(segment[V])}
+		m.depth = 0
+	}
+	return m.dir[m.index(key)]
+}
+
+// Set sets the value for the given key.
+//
+func (m *SegmentedMap[V]) Set(key int, value V) {
+	s := m.segment(key)
+	if s.m.full() && len(s.m.es) >= segMaxCap && s.depth < segMaxDepth && !s.m.Has(key) {
+		m.split(s)
+		s = m.dir[m.index(key)]
+	}
+	if _, existed := s.m.Swap(key, value); !existed {
+		m.n++
+	}
+}
+
+// split splits s into two segments, doubling the directory if needed.
+//
+func (m *SegmentedMap[V]) split(s *segment[V]) {
+	if s.depth == m.depth {
+		dir := make([]*segment[V], 2*len(m.dir))
+		for i, p := range m.dir {
+			dir[2*i], dir[2*i+1] = p, p
+		}
+		m.dir = dir
+		m.depth++
+	}
+	var ss [2]*segment[V]
+	var cnt [2]int
+	shift := 63 - s.depth
+	s.m.Range(func(k int, _ V) bool {
+		// the free key takes no slot
+		if k != s.m.free {
+			cnt[mix64(uint64(k)^m.seed)>>shift&1]++
+		}
+		return true
+	})
+	for i := range ss {
+		ss[i] = &segment[V]{depth: s.depth + 1}
+		ss[i].m.grow(cnt[i])
+	}
+	s.m.Range(func(k int, v V) bool {
+		ss[mix64(uint64(k)^m.seed)>>shift&1].m.Set(k, v)
+		return true
+	})
+	// entries pointing to s are contiguous; the first half goes to ss[0].
+	first := -1
+	for i, p := range m.dir {
+		if p == s {
+			if first < 0 {
+				first = i
+			}
+			m.dir[i] = ss[(i-first)>>(m.depth-s.depth-1)]
+		}
+	}
+	s.m = Map[V]{}
+}
+
+// Get returns the value associated with the given key and true, or the zero
+// value for V and false if the key is not set.
+//
+func (m *SegmentedMap[V]) Get(key int) (v V, ok bool) {
+	if m.dir == nil {
+		return v, false
+	}
+	return m.dir[m.index(key)].m.Get(key)
+}
+
+// Has returns true if the given key is set.
+//
+func (m *SegmentedMap[V]) Has(key int) bool {
+	if m.dir == nil {
+		return false
+	}
+	return m.dir[m.index(key)].m.Has(key)
+}
+
+// Delete deletes the given key and returns true if it was set.
+//
+func (m *SegmentedMap[V]) Delete(key int) bool {
+	if m.dir == nil || !m.dir[m.index(key)].m.Delete(key) {
+		return false
+	}
+	m.n--
+	return true
+}
+
+// Clear removes all keys from the map and releases its segments.
+//
+func (m *SegmentedMap[V]) Clear() {
+	m.dir = nil
+	m.depth = 0
+	m.n = 0
+}
+
+// Len returns the number if keys set in the map.
+//
+func (m *SegmentedMap[V]) Len() int {
+	return m.n
+}
+
+// Cap returns the capacity of the map, that is the total number of slots in
+// its segments.
+//
+func (m *SegmentedMap[V]) Cap() int {
+	c := 0
+	for i, s := range m.dir {
+		if i == 0 || s != m.dir[i-1] {
+			c += s.m.Cap()
+		}
+	}
+	return c
+}
+
+// Range calls f sequentially for each key and value present in the map. If f
+// returns false, Range stops the iteration. f must not modify the map.
+//
+func (m *SegmentedMap[V]) Range(f func(key int, value V) bool) {
+	for i, s := range m.dir {
+		if i > 0 && s == m.dir[i-1] {
+			continue
+		}
+		done := false
+		s.m.Range(func(k int, v V) bool {
+			done = !f(k, v)
+			return !done
+		})
+		if done {
+			return
+		}
+	}
+}
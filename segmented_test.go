@@ -0,0 +1,54 @@
+package intmap_test
+
+import (
+	"testing"
+
+	"github.com/db47h/intmap"
+)
+
+func TestSegmentedMap(t *testing.T) {
+	var m intmap.SegmentedMap[int]
+	const n = 50000
+	c := 0
+	for i := 0; i < n; i++ {
+		m.Set(i*7919, i)
+		// growth only ever rehashes a single bounded segment
+		if nc := m.Cap(); nc-c > 2048 {
+			t.Fatalf("capacity grew from %d to %d", c, nc)
+		} else {
+			c = nc
+		}
+	}
+	if m.Len() != n {
+		t.Fatalf("Len() = %d, expected %d", m.Len(), n)
+	}
+	for i := 0; i < n; i += 2 {
+		if !m.Delete(i * 7919) {
+			t.Errorf("Delete(%d) returned false", i*7919)
+		}
+	}
+	for i := 0; i < n; i++ {
+		v, ok := m.Get(i * 7919)
+		if ok != (i&1 != 0) || (ok && v != i) {
+			t.Errorf("bad value for key %d: %d, %v", i*7919, v, ok)
+		}
+		if m.Has(i*7919) != ok {
+			t.Errorf("Has(%d) = %v, expected %v", i*7919, !ok, ok)
+		}
+	}
+	cnt := 0
+	m.Range(func(k, v int) bool {
+		if k != v*7919 {
+			t.Errorf("Range: bad value for key %d: %d", k, v)
+		}
+		cnt++
+		return true
+	})
+	if cnt != n/2 {
+		t.Errorf("Range visited %d keys, expected %d", cnt, n/2)
+	}
+	m.Clear()
+	if m.Len() != 0 || m.Has(7919) {
+		t.Errorf("map not empty after Clear")
+	}
+}
@@ -0,0 +1,379 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+import (
+	"math/rand/v2"
+	"unsafe"
+)
+
+// Set is a set of integers. Keys are stored in a plain []int table with open
+// addressing and linear probing, so that each slot takes exactly one int. A
+// Map[struct{}] would not do: Go pads a trailing zero-size field, and each
+// KeyValue[struct{}] takes twice the size of its key.
+//
+// The zero value is an empty set ready to use. A Set must not be copied after
+// first use.
+//
+type Set struct {
+	keyTable
+	hasZero bool
+}
+
+// NewSet returns a new Set initialized with the given starting capacity and
+// fill ratio.
+//
+// See Map.Init for more details about the capacity and fillratio parameters.
+//
+func NewSet(capacity int, fillratio float32) *Set {
+	var s Set
+	s.Init(capacity, fillratio)
+	return &s
+}
+
+// Init initializes the set with the given initial capacity and fill ratio. If
+// the set already contains keys, it will be reset.
+//
+// See Map.Init for more details about the capacity and fillratio parameters.
+//
+func (s *Set) Init(capacity int, fillratio float32) {
+	s.init(capacity, fillratio)
+	s.hasZero = false
+}
+
+// SetOf returns a new Set containing the given keys.
+//
+func SetOf(keys ...int) *Set {
+	var s Set
+	s.grow(len(keys))
+	s.Add(keys...)
+	return &s
+}
+
+// grow makes room for at least n more keys without any further rehash.
+//
+func (s *Set) grow(n int) {
+	if len(s.keys) == 0 {
+		s.fillratio = defaultFillRatio
+	}
+	l := len(s.keys)
+	if l == 0 {
+		l = 8
+	}
+	for thresholdFor(l, s.fillratio) < s.size+n {
+		l <<= 1
+		if l < 0 {
+			panic("set size overflows addressable space")
+		}
+	}
+	if l != len(s.keys) {
+		s.resize(l, nil)
+	}
+}
+
+// Add adds the given keys to the set.
+//
+func (s *Set) Add(keys ...int) {
+	for _, k := range keys {
+		s.add(k)
+	}
+}
+
+func (s *Set) add(key int) {
+	if key == freeKey {
+		s.hasZero = true
+		return
+	}
+	if len(s.keys) == 0 {
+		s.grow(1)
+	}
+	idx, found := s.probe(key)
+	if found {
+		return
+	}
+	if s.size >= s.threshold {
+		s.resize(len(s.keys)*2, nil)
+		idx, _ = s.probe(key)
+	}
+	s.keys[idx] = key
+	s.size++
+}
+
+// Has returns true if key is in the set.
+//
+func (s *Set) Has(key int) bool {
+	if key == freeKey {
+		return s.hasZero
+	}
+	_, found := s.probe(key)
+	return found
+}
+
+// Remove removes key from the set and returns true if it was present.
+//
+func (s *Set) Remove(key int) bool {
+	if key == freeKey {
+		had := s.hasZero
+		s.hasZero = false
+		return had
+	}
+	idx, found := s.probe(key)
+	if !found {
+		return false
+	}
+	s.remove(idx, nil)
+	return true
+}
+
+// Len returns the number of keys in the set.
+//
+func (s *Set) Len() int {
+	if s.hasZero {
+		return s.size + 1
+	}
+	return s.size
+}
+
+// Cap returns the capacity of the set, that is the number of slots in its
+// backing array.
+//
+func (s *Set) Cap() int {
+	return len(s.keys)
+}
+
+// SizeBytes returns an estimate of the memory held by the set, in bytes,
+// including the Set structure itself and its backing array.
+//
+func (s *Set) SizeBytes() uintptr {
+	return unsafe.Sizeof(*s) + uintptr(cap(s.keys))*unsafe.Sizeof(int(0))
+}
+
+// Clear removes all keys from the set.
+//
+func (s *Set) Clear() {
+	clear(s.keys)
+	s.size = 0
+	s.hasZero = false
+}
+
+// Clone returns a copy of the set.
+//
+func (s *Set) Clone() *Set {
+	c := &Set{
+		keyTable: keyTable{
+			size:      s.size,
+			threshold: s.threshold,
+			fillratio: s.fillratio,
+			seed:      s.seed,
+		},
+		hasZero: s.hasZero,
+	}
+	if s.keys != nil {
+		c.keys = make([]int, len(s.keys))
+		copy(c.keys, s.keys)
+	}
+	return c
+}
+
+// Keys returns the keys of the set in no particular order.
+//
+func (s *Set) Keys() []int {
+	keys := make([]int, 0, s.Len())
+	s.Range(func(key int) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// Range calls f sequentially for each key in the set. If f returns false, Range
+// stops the iteration. f must not modify the set.
+//
+func (s *Set) Range(f func(key int) bool) {
+	if s.hasZero && !f(freeKey) {
+		return
+	}
+	for _, k := range s.keys {
+		if k != freeKey && !f(k) {
+			return
+		}
+	}
+}
+
+// deleteFunc removes all keys for which del returns true.
+//
+func (s *Set) deleteFunc(del func(key int) bool) {
+	if s.hasZero && del(freeKey) {
+		s.hasZero = false
+	}
+	l := len(s.keys)
+	if s.size == 0 {
+		return
+	}
+	// Start right after a free slot, see Map.DeleteFunc.
+	mod := l - 1
+	start := 0
+	for s.keys[start] != freeKey {
+		start++
+	}
+	for n, i := 0, start; n < l; n++ {
+		i = nextIdx(i) & mod
+		for {
+			k := s.keys[i]
+			if k == freeKey || !del(k) {
+				break
+			}
+			s.remove(i, nil)
+		}
+	}
+}
+
+// Union adds all keys from other to s.
+//
+func (s *Set) Union(other *Set) {
+	s.grow(other.size)
+	other.Range(func(key int) bool {
+		s.add(key)
+		return true
+	})
+}
+
+// Intersect removes from s all keys that are not in other.
+//
+func (s *Set) Intersect(other *Set) {
+	s.deleteFunc(func(key int) bool {
+		return !other.Has(key)
+	})
+}
+
+// Difference removes from s all keys that are in other.
+//
+func (s *Set) Difference(other *Set) {
+	if other.Len() < s.Len() {
+		other.Range(func(key int) bool {
+			s.Remove(key)
+			return true
+		})
+		return
+	}
+	s.deleteFunc(other.Has)
+}
+
+// SubsetOf returns true if all keys in s are also in other.
+//
+func (s *Set) SubsetOf(other *Set) bool {
+	if s.Len() > other.Len() {
+		return false
+	}
+	ok := true
+	s.Range(func(key int) bool {
+		ok = other.Has(key)
+		return ok
+	})
+	return ok
+}
+
+// Equal returns true if s and other contain the same keys.
+//
+func (s *Set) Equal(other *Set) bool {
+	return s.Len() == other.Len() && s.SubsetOf(other)
+}
+
+// keyTable is a linear probing table of int keys, with 0 as the free key. It is
+// shared by Set, which only needs keys, and SplitMap, which keeps values in a
+// parallel slice.
+//
+type keyTable struct {
+	keys      []int
+	size      int
+	threshold int
+	fillratio float32
+	seed      uint64
+}
+
+func (t *keyTable) init(capacity int, fillratio float32) {
+	capacity = nextPowerOf2(capacity)
+	if capacity < 0 {
+		panic("invalid capacity requested")
+	}
+	if capacity < 2 {
+		capacity = 2
+	}
+	t.seed = rand.Uint64()
+	t.keys = make([]int, capacity)
+	t.size = 0
+	t.fillratio = fillratio
+	t.threshold = thresholdFor(capacity, fillratio)
+}
+
+// hash returns the seeded hash of key.
+//
+func (t *keyTable) hash(key int) int {
+	return int(mix64(uint64(key) ^ t.seed))
+}
+
+func (t *keyTable) probe(key int) (idx int, found bool) {
+	mod := len(t.keys) - 1
+	if mod < 0 {
+		return -1, false
+	}
+	idx = t.hash(key) & mod
+	for {
+		switch t.keys[idx] {
+		case freeKey:
+			return idx, false
+		case key:
+			return idx, true
+		}
+		idx = nextIdx(idx) & mod
+	}
+}
+
+// resize rehashes the keys into a new table of l slots. If move is not nil, it
+// is called with the old and new slot of each key.
+//
+func (t *keyTable) resize(l int, move func(from, to int)) {
+	if len(t.keys) == 0 {
+		t.seed = rand.Uint64()
+	}
+	keys := t.keys
+	t.keys = make([]int, l)
+	t.threshold = thresholdFor(l, t.fillratio)
+	mod := l - 1
+	for i, k := range keys {
+		if k == freeKey {
+			continue
+		}
+		idx := t.hash(k) & mod
+		for t.keys[idx] != freeKey {
+			idx = nextIdx(idx) & mod
+		}
+		t.keys[idx] = k
+		if move != nil {
+			move(i, idx)
+		}
+	}
+}
+
+// remove deletes the key in slot idx. If move is not nil, it is called with the
+// new and old slot of each key shifted back. remove returns the slot left free.
+//
+func (t *keyTable) remove(idx int, move func(dst, src int)) int {
+	mod := len(t.keys) - 1
+	last := backShift(idx, mod, func(i int) int {
+		if k := t.keys[i]; k != freeKey {
+			return t.hash(k) & mod
+		}
+		return -1
+	}, func(dst, src int) {
+		t.keys[dst] = t.keys[src]
+		if move != nil {
+			move(dst, src)
+		}
+	})
+	t.keys[last] = freeKey
+	t.size--
+	return last
+}
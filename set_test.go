@@ -0,0 +1,78 @@
+package intmap_test
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"unsafe"
+
+	"github.com/db47h/intmap"
+)
+
+func TestSet(t *testing.T) {
+	a := intmap.SetOf(0, 1, 2, 3, 4, 5)
+	b := intmap.SetOf(4, 5, 6, 7)
+	check := func(name string, s *intmap.Set, exp ...int) {
+		t.Helper()
+		keys := s.Keys()
+		sort.Ints(keys)
+		if len(keys) != len(exp) {
+			t.Fatalf("%s: got %v, expected %v", name, keys, exp)
+		}
+		for i := range keys {
+			if keys[i] != exp[i] {
+				t.Fatalf("%s: got %v, expected %v", name, keys, exp)
+			}
+		}
+	}
+	u := a.Clone()
+	u.Union(b)
+	check("Union", u, 0, 1, 2, 3, 4, 5, 6, 7)
+	i := a.Clone()
+	i.Intersect(b)
+	check("Intersect", i, 4, 5)
+	d := a.Clone()
+	d.Difference(b)
+	check("Difference", d, 0, 1, 2, 3)
+	check("a", a, 0, 1, 2, 3, 4, 5)
+	if !i.SubsetOf(a) || !i.SubsetOf(b) || a.SubsetOf(b) {
+		t.Errorf("bad SubsetOf result")
+	}
+	if !a.Equal(a.Clone()) || a.Equal(u) {
+		t.Errorf("bad Equal result")
+	}
+	if !a.Remove(0) || a.Has(0) || a.Remove(0) {
+		t.Errorf("bad Remove result for key 0")
+	}
+}
+
+func TestSet_SizeBytes(t *testing.T) {
+	var s intmap.Set
+	base := s.SizeBytes()
+	s.Init(1024, 0.5)
+	if sz := s.SizeBytes() - base; sz != 1024*unsafe.Sizeof(int(0)) {
+		t.Errorf("bad size: %d bytes for 1024 slots", sz)
+	}
+	r := rand.New(rand.NewSource(42))
+	ref := make(map[int]bool)
+	for i := 0; i < 10000; i++ {
+		k := r.Intn(512) - 256
+		if r.Intn(3) == 0 {
+			if s.Remove(k) != ref[k] {
+				t.Fatalf("Remove(%d) returned %v", k, !ref[k])
+			}
+			delete(ref, k)
+			continue
+		}
+		s.Add(k)
+		ref[k] = true
+	}
+	if s.Len() != len(ref) {
+		t.Fatalf("Len() = %d, expected %d", s.Len(), len(ref))
+	}
+	for k := -300; k < 300; k++ {
+		if s.Has(k) != ref[k] {
+			t.Errorf("Has(%d) = %v", k, !ref[k])
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+import "math"
+
+// SlabMap is an integer keyed map intended for large value types. Values are
+// stored in a separate slab and the hash table only holds a 4-byte index into
+// the slab for each key, so that probe sequences stay within a compact array
+// regardless of the size of V.
+//
+// The slab only grows. Cells freed by Delete are recycled by later insertions.
+// A SlabMap can hold at most math.MaxUint32 values.
+//
+// The zero value is an empty map ready to use. A SlabMap must not be copied
+// after first use.
+//
+type SlabMap[V any] struct {
+	idx  SplitMap[uint32]
+	slab []V
+	free []uint32
+}
+
+// NewSlab returns a new SlabMap initialized with the given starting capacity
+// and fill ratio.
+//
+// See Map.Init for more details about the capacity and fillratio parameters.
+//
+func NewSlab[V any](capacity int, fillratio float32) *SlabMap[V] {
+	var m SlabMap[V]
+	m.Init(capacity, fillratio)
+	return &m
+}
+
+// Init initializes the map with the given initial capacity and fill ratio. If
+// the map already contains data, it will be reset.
+//
+// See Map.Init for more details about the capacity and fillratio parameters.
+//
+func (m *SlabMap[V]) Init(capacity int, fillratio float32) {
+	m.idx.Init(capacity, fillratio)
+	m.slab = make([]V, 0, capacity)
+	m.free = nil
+}
+
+// Set sets the value for the given key.
+//
+func (m *SlabMap[V]) Set(key int, value V) {
+	if i, ok := m.idx.Get(key); ok {
+		m.slab[i] = value
+		return
+	}
+	var i uint32
+	if n := len(m.free); n > 0 {
+		i = m.free[n-1]
+		m.free = m.free[:n-1]
+		m.slab[i] = value
+	} else {
+		if uint64(len(m.slab)) >= math.MaxUint32 {
+			panic(ErrFull)
+		}
+		i = uint32(len(m.slab))
+		m.slab = append(m.slab, value)
+	}
+	m.idx.Set(key, i)
+}
+
+// Get returns the value associated with the given key and true, or the zero
+// value for V and false if the key is not set.
+//
+func (m *SlabMap[V]) Get(key int) (v V, ok bool) {
+	if i, ok := m.idx.Get(key); ok {
+		return m.slab[i], true
+	}
+	return v, false
+}
+
+// Ptr returns a pointer to the value associated with the given key, or nil if
+// the key is not set. This avoids copying large values. The pointer is valid
+// until the next call to Set or Delete.
+//
+func (m *SlabMap[V]) Ptr(key int) *V {
+	if i, ok := m.idx.Get(key); ok {
+		return &m.slab[i]
+	}
+	return nil
+}
+
+// Has returns true if the given key is set.
+//
+func (m *SlabMap[V]) Has(key int) bool {
+	return m.idx.Has(key)
+}
+
+// Delete deletes the given key and returns true if it was set.
+//
+func (m *SlabMap[V]) Delete(key int) bool {
+	i, ok := m.idx.Get(key)
+	if !ok {
+		return false
+	}
+	m.idx.Delete(key)
+	var zero V
+	m.slab[i] = zero
+	m.free = append(m.free, i)
+	return true
+}
+
+// Clear removes all keys from the map. It keeps the allocated storage.
+//
+func (m *SlabMap[V]) Clear() {
+	m.idx.Clear()
+	var zero V
+	for i := range m.slab {
+		m.slab[i] = zero
+	}
+	m.slab = m.slab[:0]
+	m.free = m.free[:0]
+}
+
+// Len returns the number if keys set in the map.
+//
+func (m *SlabMap[V]) Len() int {
+	return m.idx.Len()
+}
+
+// Range calls f sequentially for each key and value present in the map. If f
+// returns false, Range stops the iteration. f must not modify the map.
+//
+func (m *SlabMap[V]) Range(f func(key int, value V) bool) {
+	m.idx.Range(func(key int, i uint32) bool {
+		return f(key, m.slab[i])
+	})
+}
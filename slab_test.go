@@ -0,0 +1,26 @@
+package intmap_test
+
+import (
+	"testing"
+
+	"github.com/db47h/intmap"
+)
+
+func TestSlabMap_Ptr(t *testing.T) {
+	type big [25]int64
+	var m intmap.SlabMap[big]
+	for i := 0; i < 10; i++ {
+		m.Set(i, big{int64(i)})
+	}
+	if p := m.Ptr(1); p == nil || p[0] != 1 {
+		t.Errorf("bad pointer for key 1: %v", p)
+	} else {
+		p[1] = 42
+		if v, _ := m.Get(1); v[1] != 42 {
+			t.Errorf("value not updated through pointer")
+		}
+	}
+	if p := m.Ptr(10); p != nil {
+		t.Errorf("non-nil pointer for missing key 10")
+	}
+}
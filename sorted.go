@@ -0,0 +1,53 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+import (
+	"iter"
+	"sort"
+)
+
+// SortedKeys returns a slice of the map keys in ascending order.
+//
+func (m *Map[V]) SortedKeys() []int {
+	ks := m.Keys()
+	sort.Ints(ks)
+	return ks
+}
+
+// Ascend calls fn for each key and value present in the map in ascending key
+// order. If fn returns false, Ascend stops the iteration.
+//
+// The same rules as for SortedIterator apply to fn.
+//
+func (m *Map[V]) Ascend(fn func(key int, value V) bool) {
+	m.SortedIterator()(fn)
+}
+
+// SortedIterator returns an iterator over the map's key/value pairs in
+// ascending key order.
+//
+// The set of keys to visit is collected into a buffer owned by the map, which
+// is reused by subsequent calls, before the first iteration. The loop body may
+// then freely modify the map: keys deleted before being visited are skipped,
+// and keys inserted during the iteration are not visited.
+//
+func (m *Map[V]) SortedIterator() iter.Seq2[int, V] {
+	return func(yield func(int, V) bool) {
+		// take ownership of the buffer in case of nested iterations
+		ks := m.AppendKeys(m.sortBuf[:0])
+		m.sortBuf = nil
+		defer func() {
+			clear(ks)
+			m.sortBuf = ks[:0]
+		}()
+		sort.Ints(ks)
+		for _, k := range ks {
+			if v, ok := m.Get(k); ok && !yield(k, v) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package intmap_test
+
+import (
+	"testing"
+
+	"github.com/db47h/intmap"
+)
+
+func TestMap_SortedKeys(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 100; k > -100; k-- {
+		m.Set(k, k)
+	}
+	ks := m.SortedKeys()
+	if len(ks) != 200 {
+		t.Fatalf("bad length: expected 200, got %d", len(ks))
+	}
+	for i, k := range ks {
+		if k != i-99 {
+			t.Errorf("bad key at %d: expected %d, got %d", i, i-99, k)
+		}
+	}
+}
+
+func TestMap_Ascend(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 100; k > -100; k-- {
+		m.Set(k, -k)
+	}
+	prev := -100
+	m.Ascend(func(k, v int) bool {
+		if k <= prev || v != -k {
+			t.Errorf("bad entry %d: %d after %d", k, v, prev)
+		}
+		prev = k
+		m.Delete(k + 1)
+		return true
+	})
+	if m.Len() != 100 {
+		t.Errorf("bad size: expected 100, got %d", m.Len())
+	}
+}
+
+func TestMap_SortedIterator(t *testing.T) {
+	var m intmap.Map[int]
+	for k := 100; k > -100; k-- {
+		m.Set(k, -k)
+	}
+	for pass := 0; pass < 2; pass++ {
+		prev, n := -100, 0
+		for k, v := range m.SortedIterator() {
+			if k <= prev || v != -k {
+				t.Errorf("bad entry %d: %d after %d", k, v, prev)
+			}
+			prev = k
+			n++
+		}
+		if n != 200 {
+			t.Errorf("bad number of keys: expected 200, got %d", n)
+		}
+	}
+	allocs := testing.AllocsPerRun(10, func() {
+		for range m.SortedIterator() {
+		}
+	})
+	if allocs > 2 {
+		t.Errorf("too many allocations: %v", allocs)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+// SplitMap is an integer keyed map that uses a struct-of-arrays layout: keys
+// are densely packed in their own slice and values are kept in a parallel
+// slice. Probing only touches the key slice, which makes lookups much more
+// cache friendly than with Map when V is large. The key slice also contains no
+// pointers and is therefore never scanned by the garbage collector.
+//
+// SplitMap implements the core subset of the Map API. The zero value is an
+// empty map ready to use. A SplitMap must not be copied after first use.
+//
+type SplitMap[V any] struct {
+	keyTable
+	values       []V
+	hasFreeKey   bool
+	freeKeyValue V
+}
+
+// NewSplit returns a new SplitMap initialized with the given starting capacity
+// and fill ratio.
+//
+// See Map.Init for more details about the capacity and fillratio parameters.
+//
+func NewSplit[V any](capacity int, fillratio float32) *SplitMap[V] {
+	var m SplitMap[V]
+	m.Init(capacity, fillratio)
+	return &m
+}
+
+// Init initializes the map with the given initial capacity and fill ratio. If
+// the map already contains data, it will be reset.
+//
+// See Map.Init for more details about the capacity and fillratio parameters.
+//
+func (m *SplitMap[V]) Init(capacity int, fillratio float32) {
+	m.init(capacity, fillratio)
+	m.values = make([]V, len(m.keys))
+	m.hasFreeKey = false
+	var zero V
+	m.freeKeyValue = zero
+}
+
+// Set sets the value for the given key.
+//
+func (m *SplitMap[V]) Set(key int, value V) {
+	if key == freeKey {
+		m.freeKeyValue = value
+		m.hasFreeKey = true
+		return
+	}
+	if len(m.keys) == 0 {
+		m.fillratio = defaultFillRatio
+		m.resize(8)
+	}
+	idx, found := m.probe(key)
+	if found {
+		m.values[idx] = value
+		return
+	}
+	if m.size >= m.threshold {
+		m.resize(len(m.keys) * 2)
+		idx, _ = m.probe(key)
+	}
+	m.keys[idx] = key
+	m.values[idx] = value
+	m.size++
+}
+
+// Get returns the value associated with the given key and true, or the zero
+// value for V and false if the key is not set.
+//
+func (m *SplitMap[V]) Get(key int) (v V, ok bool) {
+	if key == freeKey {
+		if m.hasFreeKey {
+			return m.freeKeyValue, true
+		}
+		return v, false
+	}
+	if idx, found := m.probe(key); found {
+		return m.values[idx], true
+	}
+	return v, false
+}
+
+// Has returns true if the given key is set.
+//
+func (m *SplitMap[V]) Has(key int) bool {
+	if key == freeKey {
+		return m.hasFreeKey
+	}
+	_, found := m.probe(key)
+	return found
+}
+
+// Delete deletes the given key and returns true if it was set.
+//
+func (m *SplitMap[V]) Delete(key int) bool {
+	if key == freeKey {
+		if !m.hasFreeKey {
+			return false
+		}
+		var zero V
+		m.hasFreeKey = false
+		m.freeKeyValue = zero
+		return true
+	}
+	idx, found := m.probe(key)
+	if !found {
+		return false
+	}
+	last := m.remove(idx, func(dst, src int) {
+		m.values[dst] = m.values[src]
+	})
+	var zero V
+	m.values[last] = zero
+	return true
+}
+
+// Clear removes all keys from the map. It keeps the allocated backing arrays.
+//
+func (m *SplitMap[V]) Clear() {
+	var zero V
+	for i := range m.keys {
+		m.keys[i] = freeKey
+		m.values[i] = zero
+	}
+	m.size = 0
+	m.hasFreeKey = false
+	m.freeKeyValue = zero
+}
+
+// Len returns the number if keys set in the map.
+//
+func (m *SplitMap[V]) Len() int {
+	if m.hasFreeKey {
+		return m.size + 1
+	}
+	return m.size
+}
+
+// Cap returns the capacity of the map, that is the number of slots in its
+// backing arrays.
+//
+func (m *SplitMap[V]) Cap() int {
+	return len(m.keys)
+}
+
+// Range calls f sequentially for each key and value present in the map. If f
+// returns false, Range stops the iteration. f must not modify the map.
+//
+func (m *SplitMap[V]) Range(f func(key int, value V) bool) {
+	if m.hasFreeKey && !f(freeKey, m.freeKeyValue) {
+		return
+	}
+	for i, k := range m.keys {
+		if k != freeKey && !f(k, m.values[i]) {
+			return
+		}
+	}
+}
+
+func (m *SplitMap[V]) resize(l int) {
+	values := m.values
+	m.values = make([]V, l)
+	m.keyTable.resize(l, func(from, to int) {
+		m.values[to] = values[from]
+	})
+}
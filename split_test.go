@@ -0,0 +1,33 @@
+package intmap_test
+
+import (
+	"testing"
+
+	"github.com/db47h/intmap"
+)
+
+func TestSplitMap_Clear(t *testing.T) {
+	var m intmap.SplitMap[[4]int]
+	for i := 0; i < 100; i++ {
+		m.Set(i, [4]int{i})
+	}
+	m.Clear()
+	if m.Len() != 0 || m.Has(1) {
+		t.Errorf("map not empty after Clear")
+	}
+	m.Set(1, [4]int{1})
+	if v, ok := m.Get(1); !ok || v[0] != 1 {
+		t.Errorf("bad value for key 1 after Clear: %v, %v", v, ok)
+	}
+}
+
+func TestSplitMap_ZeroValueFillRatio(t *testing.T) {
+	var m intmap.SplitMap[int]
+	// the default fill ratio of 87.5% leaves room for 7 keys in 8 slots
+	for k := 1; k <= 7; k++ {
+		m.Set(k, k)
+	}
+	if m.Cap() != 8 {
+		t.Errorf("Cap() = %d with 7 keys, expected 8", m.Cap())
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+// Stats holds statistics about the layout of a Map.
+//
+// The probe length of a key is the number of slots looked at before the slot
+// where it is actually stored, starting from its home slot given by its hash.
+// The free key, which is stored separately, is not accounted for in probe
+// statistics.
+//
+type Stats struct {
+	Len        int     // number of keys
+	Cap        int     // capacity
+	LoadFactor float32 // ratio of used slots to capacity
+	MaxProbe   int     // longest probe length
+	MeanProbe  float64 // average probe length
+	// Histogram[i] is the number of keys with a probe length of i. The last
+	// bucket counts keys with a probe length of len(Histogram)-1 or more.
+	Histogram [8]int
+}
+
+// Stats computes statistics about the map layout. It walks the whole backing
+// array.
+//
+func (m *Map[V]) Stats() Stats {
+	s := Stats{
+		Len:        m.Len(),
+		Cap:        m.Cap(),
+		LoadFactor: m.LoadFactor(),
+	}
+	mod := len(m.es) - 1
+	total := 0
+	for i := range m.es {
+		k := m.es[i].Key
+		if k == m.free {
+			continue
+		}
+		p := m.probeLen(i, mod)
+		total += p
+		if p > s.MaxProbe {
+			s.MaxProbe = p
+		}
+		if p >= len(s.Histogram) {
+			p = len(s.Histogram) - 1
+		}
+		s.Histogram[p]++
+	}
+	if m.size > 0 {
+		s.MeanProbe = float64(total) / float64(m.size)
+	}
+	return s
+}
+
+// probeLen returns the probe length of the key in slot i.
+//
+func (m *Map[V]) probeLen(i, mod int) int {
+	idx := m.slot(m.es[i].Key, mod)
+	n := 0
+	for idx != i {
+		n++
+		idx = m.next(idx, n, mod)
+	}
+	return n
+}
@@ -0,0 +1,29 @@
+package intmap_test
+
+import (
+	"testing"
+
+	"github.com/db47h/intmap"
+)
+
+func TestMap_Stats(t *testing.T) {
+	m := intmap.New[int](1024, 0.9)
+	for k := 0; k < 900; k++ {
+		m.Set(k*7, k)
+	}
+	s := m.Stats()
+	if s.Len != 900 || s.Cap != 1024 {
+		t.Fatalf("bad Len or Cap: %d, %d", s.Len, s.Cap)
+	}
+	n := 0
+	for _, c := range s.Histogram {
+		n += c
+	}
+	// key 0 is not accounted for
+	if n != s.Len-1 {
+		t.Errorf("histogram counts %d keys, expected %d", n, s.Len-1)
+	}
+	if s.MeanProbe < 0 || s.MeanProbe > float64(s.MaxProbe) {
+		t.Errorf("bad mean probe length %v with max %d", s.MeanProbe, s.MaxProbe)
+	}
+}
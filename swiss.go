@@ -0,0 +1,239 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+import (
+	"math/bits"
+	"math/rand/v2"
+)
+
+const (
+	groupSize   = 16
+	ctrlEmpty   = 0x80
+	ctrlDeleted = 0xfe
+)
+
+// SwissMap is an integer keyed map in the style of Abseil's SwissTable. Slots
+// are organized in groups of 16, and a parallel array of control bytes holds,
+// for each slot, either a 7 bits fingerprint of the hash of its key or a marker
+// for empty and deleted slots. Probing a group compares all 16 fingerprints at
+// once, so that most probes never touch the key/value entries of non-matching
+// slots. Groups are probed with SSE2 instructions on amd64 and NEON
+// instructions on arm64; other platforms, or builds with the purego tag,
+// process each group as two 64 bits words (SWAR).
+//
+// Deleted slots are marked with tombstones unless their group is not full, so
+// that deletions do not move any entries. The zero key needs no special
+// handling. The load factor is fixed at 87.5%.
+//
+// The zero value is an empty map ready to use. A SwissMap must not be copied
+// after first use.
+//
+type SwissMap[V any] struct {
+	ctrl       []byte
+	slots      []KeyValue[V]
+	size       int
+	growthLeft int // number of empty slots that can still be filled before a rehash
+	seed       uint64
+}
+
+// NewSwiss returns a new SwissMap initialized with the given starting
+// capacity.
+//
+func NewSwiss[V any](capacity int) *SwissMap[V] {
+	var m SwissMap[V]
+	m.Init(capacity)
+	return &m
+}
+
+// Init initializes the map with room for at least capacity keys. If the map
+// already contains data, it will be reset.
+//
+func (m *SwissMap[V]) Init(capacity int) {
+	// the load factor is 7/8: capacity keys need capacity*8/7 slots
+	n := nextPowerOf2(((capacity*8+6)/7 + groupSize - 1) / groupSize)
+	if n < 1 {
+		n = 1
+	}
+	if n*groupSize < 0 {
+		panic("invalid capacity requested")
+	}
+	m.seed = rand.Uint64()
+	m.alloc(n * groupSize)
+}
+
+func (m *SwissMap[V]) alloc(n int) {
+	m.ctrl = make([]byte, n)
+	for i := range m.ctrl {
+		m.ctrl[i] = ctrlEmpty
+	}
+	m.slots = make([]KeyValue[V], n)
+	m.size = 0
+	m.growthLeft = n - n/8
+}
+
+// hash returns the index of the first group to probe and the fingerprint of
+// key.
+//
+func (m *SwissMap[V]) hash(key int) (h1 uint, h2 byte) {
+	x := mix64(uint64(key) ^ m.seed)
+	return uint(x), byte(x >> 57)
+}
+
+// group returns the control bytes of group g.
+//
+func (m *SwissMap[V]) group(g uint) *[groupSize]byte {
+	return (*[groupSize]byte)(m.ctrl[g*groupSize:])
+}
+
+// find returns the index of the slot holding key and true, or -1 and false if
+// the key is not in the map.
+//
+func (m *SwissMap[V]) find(key int) (int, bool) {
+	if len(m.ctrl) == 0 {
+		return -1, false
+	}
+	h1, h2 := m.hash(key)
+	mask := uint(len(m.ctrl)/groupSize - 1)
+	g := h1 & mask
+	for step := uint(1); ; step++ {
+		grp := m.group(g)
+		for b := matchH2(grp, h2); b != 0; b &= b - 1 {
+			i := int(g*groupSize) + bits.TrailingZeros32(b)
+			if m.slots[i].Key == key {
+				return i, true
+			}
+		}
+		if matchEmpty(grp) != 0 {
+			return -1, false
+		}
+		g = (g + step) & mask
+	}
+}
+
+// Set sets the value for the given key.
+//
+func (m *SwissMap[V]) Set(key int, value V) {
+	if i, ok := m.find(key); ok {
+		m.slots[i].Value = value
+		return
+	}
+	if m.growthLeft == 0 {
+		m.rehash()
+	}
+	m.insert(key, value)
+}
+
+// insert inserts a new key in the first empty or deleted slot of its probe
+// sequence. There must be at least one empty slot left in the map.
+//
+func (m *SwissMap[V]) insert(key int, value V) {
+	h1, h2 := m.hash(key)
+	mask := uint(len(m.ctrl)/groupSize - 1)
+	g := h1 & mask
+	for step := uint(1); ; step++ {
+		if b := matchEmptyOrDeleted(m.group(g)); b != 0 {
+			i := int(g*groupSize) + bits.TrailingZeros32(b)
+			if m.ctrl[i] == ctrlEmpty {
+				m.growthLeft--
+			}
+			m.ctrl[i] = h2
+			m.slots[i] = KeyValue[V]{key, value}
+			m.size++
+			return
+		}
+		g = (g + step) & mask
+	}
+}
+
+// rehash rebuilds the table, doubling its size unless enough slots can be
+// reclaimed from tombstones.
+//
+func (m *SwissMap[V]) rehash() {
+	ctrl, slots := m.ctrl, m.slots
+	n := len(ctrl)
+	switch {
+	case n == 0:
+		m.seed = rand.Uint64()
+		n = groupSize
+	case m.size >= n*7/16:
+		n *= 2
+		if n < 0 {
+			panic("map size overflows addressable space")
+		}
+	}
+	m.alloc(n)
+	for i, c := range ctrl {
+		if c&ctrlEmpty == 0 {
+			m.insert(slots[i].Key, slots[i].Value)
+		}
+	}
+}
+
+// Get returns the value associated with the given key and true, or the zero
+// value for V and false if the key is not set.
+//
+func (m *SwissMap[V]) Get(key int) (v V, ok bool) {
+	if i, ok := m.find(key); ok {
+		return m.slots[i].Value, true
+	}
+	return v, false
+}
+
+// Has returns true if the given key is set.
+//
+func (m *SwissMap[V]) Has(key int) bool {
+	_, ok := m.find(key)
+	return ok
+}
+
+// Delete deletes the given key and returns true if it was set.
+//
+func (m *SwissMap[V]) Delete(key int) bool {
+	i, ok := m.find(key)
+	if !ok {
+		return false
+	}
+	// A probe sequence stops at the first group with an empty slot, so if the
+	// group already has one, no probe sequence can go past it and the slot can
+	// be marked empty.
+	if matchEmpty(m.group(uint(i/groupSize))) != 0 {
+		m.ctrl[i] = ctrlEmpty
+		m.growthLeft++
+	} else {
+		m.ctrl[i] = ctrlDeleted
+	}
+	m.slots[i] = KeyValue[V]{}
+	m.size--
+	return true
+}
+
+// Clear removes all keys from the map. It keeps the allocated storage.
+//
+func (m *SwissMap[V]) Clear() {
+	for i := range m.ctrl {
+		m.ctrl[i] = ctrlEmpty
+	}
+	clear(m.slots)
+	m.size = 0
+	m.growthLeft = len(m.ctrl) - len(m.ctrl)/8
+}
+
+// Len returns the number if keys set in the map.
+//
+func (m *SwissMap[V]) Len() int {
+	return m.size
+}
+
+// Range calls f sequentially for each key and value present in the map. If f
+// returns false, Range stops the iteration. f must not modify the map.
+//
+func (m *SwissMap[V]) Range(f func(key int, value V) bool) {
+	for i, c := range m.ctrl {
+		if c&ctrlEmpty == 0 && !f(m.slots[i].Key, m.slots[i].Value) {
+			return
+		}
+	}
+}
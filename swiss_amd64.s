@@ -0,0 +1,41 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// func matchH2(grp *[16]byte, h2 byte) uint32
+TEXT ·matchH2(SB), NOSPLIT, $0-20
+	MOVQ    grp+0(FP), AX
+	MOVBLZX h2+8(FP), BX
+	MOVQ    BX, X0
+	PUNPCKLBW X0, X0
+	PUNPCKLWL X0, X0
+	PSHUFL  $0, X0, X0
+	MOVOU   (AX), X1
+	PCMPEQB X0, X1
+	PMOVMSKB X1, AX
+	MOVL    AX, ret+16(FP)
+	RET
+
+// func matchEmpty(grp *[16]byte) uint32
+TEXT ·matchEmpty(SB), NOSPLIT, $0-12
+	MOVQ    grp+0(FP), AX
+	MOVL    $0x80808080, BX
+	MOVQ    BX, X0
+	PSHUFL  $0, X0, X0
+	MOVOU   (AX), X1
+	PCMPEQB X0, X1
+	PMOVMSKB X1, AX
+	MOVL    AX, ret+8(FP)
+	RET
+
+// func matchEmptyOrDeleted(grp *[16]byte) uint32
+TEXT ·matchEmptyOrDeleted(SB), NOSPLIT, $0-12
+	MOVQ    grp+0(FP), AX
+	MOVOU   (AX), X0
+	PMOVMSKB X0, AX
+	MOVL    AX, ret+8(FP)
+	RET
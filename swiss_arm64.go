@@ -0,0 +1,23 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build arm64 && !purego
+
+package intmap
+
+// matchH2 returns a bitmask of the slots of a group whose control byte is h2.
+//
+//go:noescape
+func matchH2(grp *[groupSize]byte, h2 byte) uint32
+
+// matchEmpty returns a bitmask of the empty slots of a group.
+//
+//go:noescape
+func matchEmpty(grp *[groupSize]byte) uint32
+
+// matchEmptyOrDeleted returns a bitmask of the empty or deleted slots of a
+// group.
+//
+//go:noescape
+func matchEmptyOrDeleted(grp *[groupSize]byte) uint32
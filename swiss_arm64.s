@@ -0,0 +1,63 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build arm64 && !purego
+
+#include "textflag.h"
+
+// NEON has no equivalent of PMOVMSKB. Compared bytes are set to 0xff or 0, and
+// masked with the weight of their bit in a 8 bits half of the result. Three
+// pairwise additions then sum each half into one byte.
+
+// func matchH2(grp *[16]byte, h2 byte) uint32
+TEXT ·matchH2(SB), NOSPLIT, $0-20
+	MOVD  grp+0(FP), R0
+	MOVBU h2+8(FP), R1
+	VLD1  (R0), [V0.B16]
+	VDUP  R1, V1.B16
+	VCMEQ V0.B16, V1.B16, V0.B16
+	MOVD  $0x8040201008040201, R2
+	VDUP  R2, V1.D2
+	VAND  V1.B16, V0.B16, V0.B16
+	VADDP V0.B16, V0.B16, V0.B16
+	VADDP V0.B16, V0.B16, V0.B16
+	VADDP V0.B16, V0.B16, V0.B16
+	VMOV  V0.H[0], R0
+	MOVW  R0, ret+16(FP)
+	RET
+
+// func matchEmpty(grp *[16]byte) uint32
+TEXT ·matchEmpty(SB), NOSPLIT, $0-12
+	MOVD  grp+0(FP), R0
+	VLD1  (R0), [V0.B16]
+	MOVD  $0x80, R1
+	VDUP  R1, V1.B16
+	VCMEQ V0.B16, V1.B16, V0.B16
+	MOVD  $0x8040201008040201, R2
+	VDUP  R2, V1.D2
+	VAND  V1.B16, V0.B16, V0.B16
+	VADDP V0.B16, V0.B16, V0.B16
+	VADDP V0.B16, V0.B16, V0.B16
+	VADDP V0.B16, V0.B16, V0.B16
+	VMOV  V0.H[0], R0
+	MOVW  R0, ret+8(FP)
+	RET
+
+// func matchEmptyOrDeleted(grp *[16]byte) uint32
+TEXT ·matchEmptyOrDeleted(SB), NOSPLIT, $0-12
+	MOVD  grp+0(FP), R0
+	VLD1  (R0), [V0.B16]
+	MOVD  $0x80, R1
+	VDUP  R1, V1.B16
+	VAND  V1.B16, V0.B16, V0.B16
+	VCMEQ V0.B16, V1.B16, V0.B16
+	MOVD  $0x8040201008040201, R2
+	VDUP  R2, V1.D2
+	VAND  V1.B16, V0.B16, V0.B16
+	VADDP V0.B16, V0.B16, V0.B16
+	VADDP V0.B16, V0.B16, V0.B16
+	VADDP V0.B16, V0.B16, V0.B16
+	VMOV  V0.H[0], R0
+	MOVW  R0, ret+8(FP)
+	RET
@@ -0,0 +1,27 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build !(amd64 || arm64) || purego
+
+package intmap
+
+// matchH2 returns a bitmask of the slots of a group whose control byte is h2.
+// It may yield false positives, which are weeded out by comparing keys.
+//
+func matchH2(grp *[groupSize]byte, h2 byte) uint32 {
+	return swarMatchH2(grp, h2)
+}
+
+// matchEmpty returns a bitmask of the empty slots of a group.
+//
+func matchEmpty(grp *[groupSize]byte) uint32 {
+	return swarMatchEmpty(grp)
+}
+
+// matchEmptyOrDeleted returns a bitmask of the empty or deleted slots of a
+// group.
+//
+func matchEmptyOrDeleted(grp *[groupSize]byte) uint32 {
+	return swarMatchEmptyOrDeleted(grp)
+}
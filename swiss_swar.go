@@ -0,0 +1,49 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+import "encoding/binary"
+
+// This file implements group probing with plain 64 bits arithmetic (SWAR). It
+// is used on platforms without an assembly version, and as a reference for the
+// assembly versions in tests.
+
+const (
+	bitsetLSB = 0x0101010101010101
+	bitsetMSB = 0x8080808080808080
+)
+
+// compact packs the high bits of each byte of an 8 bytes bitset into the low 8
+// bits of the result.
+//
+func compact(b uint64) uint32 {
+	return uint32((b >> 7) * 0x0102040810204080 >> 56)
+}
+
+// swarMatchH2 returns a bitmask of the slots of a group whose control byte is
+// h2. It may yield false positives, which are weeded out by comparing keys.
+//
+func swarMatchH2(grp *[groupSize]byte, h2 byte) uint32 {
+	lo := binary.LittleEndian.Uint64(grp[:8]) ^ (bitsetLSB * uint64(h2))
+	hi := binary.LittleEndian.Uint64(grp[8:]) ^ (bitsetLSB * uint64(h2))
+	return compact((lo-bitsetLSB)&^lo&bitsetMSB) | compact((hi-bitsetLSB)&^hi&bitsetMSB)<<8
+}
+
+// swarMatchEmpty returns a bitmask of the empty slots of a group.
+//
+func swarMatchEmpty(grp *[groupSize]byte) uint32 {
+	lo := binary.LittleEndian.Uint64(grp[:8])
+	hi := binary.LittleEndian.Uint64(grp[8:])
+	return compact(lo&^(lo<<6)&bitsetMSB) | compact(hi&^(hi<<6)&bitsetMSB)<<8
+}
+
+// swarMatchEmptyOrDeleted returns a bitmask of the empty or deleted slots of a
+// group.
+//
+func swarMatchEmptyOrDeleted(grp *[groupSize]byte) uint32 {
+	lo := binary.LittleEndian.Uint64(grp[:8])
+	hi := binary.LittleEndian.Uint64(grp[8:])
+	return compact(lo&bitsetMSB) | compact(hi&bitsetMSB)<<8
+}
@@ -0,0 +1,167 @@
+package intmap_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/db47h/intmap"
+)
+
+// countCtrl returns the number of control bytes of m equal to c.
+func countCtrl(m *intmap.SwissMap[int], c byte) int {
+	n := 0
+	for _, b := range intmap.SwissCtrl(m) {
+		if b == c {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSwissMap_Init(t *testing.T) {
+	for c := 0; c <= 1000; c++ {
+		m := intmap.NewSwiss[int](c)
+		n := len(intmap.SwissCtrl(m))
+		// the load factor is 7/8, and there are at least 16 slots
+		if n*7/8 < c || n > 16 && n/2*7/8 >= c {
+			t.Fatalf("capacity %d: %d slots", c, n)
+		}
+		ctrl := intmap.SwissCtrl(m)
+		for k := 0; k < c; k++ {
+			m.Set(k, k)
+		}
+		if len(intmap.SwissCtrl(m)) != n || &intmap.SwissCtrl(m)[0] != &ctrl[0] {
+			t.Fatalf("capacity %d: map rehashed before holding %d keys", c, c)
+		}
+	}
+}
+
+func TestSwissMap_Groups(t *testing.T) {
+	const (
+		empty   = 0x80
+		deleted = 0xfe
+	)
+	m := intmap.NewSwiss[int](28)
+	if n := len(intmap.SwissCtrl(m)); n != 32 {
+		t.Fatalf("%d slots, expected 32", n)
+	}
+	// 20 keys whose probe sequence starts at the last group: the last 4 wrap
+	// around to the first group.
+	var keys []int
+	for k := 1; len(keys) < 20; k++ {
+		if intmap.SwissGroup(m, k) == 1 {
+			keys = append(keys, k)
+		}
+	}
+	for _, k := range keys {
+		m.Set(k, k)
+	}
+	check := func(when string) {
+		t.Helper()
+		for _, k := range keys {
+			if v, ok := m.Get(k); !ok || v != k {
+				t.Fatalf("%s: bad value for key %d: %d, %v", when, k, v, ok)
+			}
+		}
+		if m.Len() != len(keys) {
+			t.Fatalf("%s: Len() = %d, expected %d", when, m.Len(), len(keys))
+		}
+	}
+	check("after wraparound")
+
+	// the last group is full: a deleted key leaves a tombstone so that the
+	// keys that wrapped around can still be found.
+	m.Delete(keys[0])
+	keys = keys[1:]
+	if n := countCtrl(m, deleted); n != 1 {
+		t.Fatalf("%d deleted slots after deleting from a full group, expected 1", n)
+	}
+	check("after deletion from a full group")
+
+	// the first group has empty slots: no tombstone is needed.
+	m.Delete(keys[len(keys)-1])
+	keys = keys[:len(keys)-1]
+	if n := countCtrl(m, deleted); n != 1 {
+		t.Fatalf("%d deleted slots after deleting from a non-full group, expected 1", n)
+	}
+	if n := countCtrl(m, empty); n != 32-len(keys)-1 {
+		t.Fatalf("%d empty slots, expected %d", n, 32-len(keys)-1)
+	}
+	check("after deletion from a non-full group")
+
+	// a new key with the same probe sequence reuses the tombstone.
+	for k := keys[len(keys)-1] + 1; ; k++ {
+		if intmap.SwissGroup(m, k) == 1 {
+			m.Set(k, k)
+			keys = append(keys, k)
+			break
+		}
+	}
+	if n := countCtrl(m, deleted); n != 0 {
+		t.Fatalf("%d deleted slots after reusing a tombstone, expected 0", n)
+	}
+	check("after reusing a tombstone")
+
+	// growth
+	for k := -1; m.Len() < 100; k-- {
+		m.Set(k, k)
+		keys = append(keys, k)
+	}
+	if n := len(intmap.SwissCtrl(m)); n != 128 {
+		t.Fatalf("%d slots after growth, expected 128", n)
+	}
+	check("after growth")
+}
+
+func TestSwissMatch(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	var grp [16]byte
+	for n := 0; n < 10000; n++ {
+		for i := range grp {
+			switch r.Intn(4) {
+			case 0:
+				grp[i] = 0x80 // empty
+			case 1:
+				grp[i] = 0xfe // deleted
+			default:
+				grp[i] = byte(r.Intn(8)) // small range to get matches
+			}
+		}
+		h2 := byte(r.Intn(8))
+		var want uint32
+		for i, c := range grp {
+			if c == h2 {
+				want |= 1 << i
+			}
+		}
+		// SWAR may yield false positives, but no false negatives.
+		swar := intmap.SWARMatchH2(&grp, h2)
+		if swar&want != want {
+			t.Fatalf("swarMatchH2(%x, %x) = %016b, expected %016b", grp, h2, swar, want)
+		}
+		if got := intmap.MatchH2(&grp, h2); got&want != want || got&^swar != 0 {
+			t.Fatalf("matchH2(%x, %x) = %016b, expected %016b", grp, h2, got, want)
+		}
+		if got, want := intmap.MatchEmpty(&grp), intmap.SWARMatchEmpty(&grp); got != want {
+			t.Fatalf("matchEmpty(%x) = %016b, expected %016b", grp, got, want)
+		}
+		if got, want := intmap.MatchEmptyOrDeleted(&grp), intmap.SWARMatchEmptyOrDeleted(&grp); got != want {
+			t.Fatalf("matchEmptyOrDeleted(%x) = %016b, expected %016b", grp, got, want)
+		}
+	}
+}
+
+func BenchmarkSwissMapGet(b *testing.B) {
+	var m intmap.SwissMap[Value]
+	for i := 0; i < *keyMax; i++ {
+		m.Set(i, Value(i))
+	}
+	rand.Seed(424242)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v, ok := m.Get(rand.Intn(*keyMax))
+		if ok {
+			result = v
+		}
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+import (
+	"math/rand/v2"
+	"unsafe"
+)
+
+// table is the linear probing map shared by MapOf, Map32, Map64 and PtrMap. K
+// must be an integer or pointer type, whose zero value plays the role of the
+// free key.
+//
+type table[K comparable, V any] struct {
+	es           []KeyValueOf[K, V]
+	size         int
+	threshold    int
+	fillratio    float32
+	seed         uint64
+	hasFreeKey   bool
+	freeKeyValue V
+}
+
+// Init initializes the map with the given initial capacity and fill ratio. If
+// the map already contains data, it will be reset.
+//
+// See Map.Init for more details about the capacity and fillratio parameters.
+//
+func (m *table[K, V]) Init(capacity int, fillratio float32) {
+	capacity = nextPowerOf2(capacity)
+	if capacity < 0 {
+		panic("invalid capacity requested")
+	}
+	if capacity < 2 {
+		capacity = 2
+	}
+	m.seed = rand.Uint64()
+	m.es = make([]KeyValueOf[K, V], capacity)
+	m.size = 0
+	m.fillratio = fillratio
+	m.threshold = thresholdFor(capacity, fillratio)
+	m.hasFreeKey = false
+	var zero V
+	m.freeKeyValue = zero
+}
+
+// Set sets the value for the given key.
+//
+func (m *table[K, V]) Set(key K, value V) {
+	var free K
+	if key == free {
+		m.freeKeyValue = value
+		m.hasFreeKey = true
+		return
+	}
+	if len(m.es) == 0 {
+		m.fillratio = defaultFillRatio
+		m.resize(8)
+	}
+	idx, found := m.probe(key)
+	if found {
+		m.es[idx].Value = value
+		return
+	}
+	if m.size >= m.threshold {
+		m.resize(len(m.es) * 2)
+		idx, _ = m.probe(key)
+	}
+	m.es[idx] = KeyValueOf[K, V]{key, value}
+	m.size++
+}
+
+// Get returns the value associated with the given key and true, or the zero
+// value for V and false if the key is not set.
+//
+func (m *table[K, V]) Get(key K) (v V, ok bool) {
+	var free K
+	if key == free {
+		if m.hasFreeKey {
+			return m.freeKeyValue, true
+		}
+		return v, false
+	}
+	if idx, found := m.probe(key); found {
+		return m.es[idx].Value, true
+	}
+	return v, false
+}
+
+// Has returns true if the given key is set.
+//
+func (m *table[K, V]) Has(key K) bool {
+	var free K
+	if key == free {
+		return m.hasFreeKey
+	}
+	_, found := m.probe(key)
+	return found
+}
+
+// Delete deletes the given key and returns true if it was set.
+//
+func (m *table[K, V]) Delete(key K) bool {
+	var free K
+	if key == free {
+		if !m.hasFreeKey {
+			return false
+		}
+		var zero V
+		m.hasFreeKey = false
+		m.freeKeyValue = zero
+		return true
+	}
+	idx, found := m.probe(key)
+	if !found {
+		return false
+	}
+	mod := len(m.es) - 1
+	last := backShift(idx, mod, func(i int) int {
+		if k := m.es[i].Key; k != free {
+			return m.hash(k) & mod
+		}
+		return -1
+	}, func(dst, src int) {
+		m.es[dst] = m.es[src]
+	})
+	m.es[last] = KeyValueOf[K, V]{}
+	m.size--
+	return true
+}
+
+// Clear removes all keys from the map. It keeps the allocated backing array.
+//
+func (m *table[K, V]) Clear() {
+	clear(m.es)
+	var zero V
+	m.size = 0
+	m.hasFreeKey = false
+	m.freeKeyValue = zero
+}
+
+// Len returns the number if keys set in the map.
+//
+func (m *table[K, V]) Len() int {
+	if m.hasFreeKey {
+		return m.size + 1
+	}
+	return m.size
+}
+
+// Cap returns the capacity of the map, that is the number of slots in its
+// backing array.
+//
+func (m *table[K, V]) Cap() int {
+	return len(m.es)
+}
+
+// Keys returns the keys of the map in no particular order.
+//
+func (m *table[K, V]) Keys() []K {
+	var free K
+	keys := make([]K, 0, m.Len())
+	if m.hasFreeKey {
+		keys = append(keys, free)
+	}
+	for i := range m.es {
+		if k := m.es[i].Key; k != free {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Range calls f sequentially for each key and value present in the map. If f
+// returns false, Range stops the iteration. f must not modify the map.
+//
+func (m *table[K, V]) Range(f func(key K, value V) bool) {
+	var free K
+	if m.hasFreeKey && !f(free, m.freeKeyValue) {
+		return
+	}
+	for i := range m.es {
+		if e := &m.es[i]; e.Key != free && !f(e.Key, e.Value) {
+			return
+		}
+	}
+}
+
+// hash returns the seeded hash of key.
+//
+func (m *table[K, V]) hash(key K) int {
+	return int(mix64(keyBits(key) ^ m.seed))
+}
+
+// keyBits returns the bits of key, an integer or pointer, zero-extended to 64
+// bits.
+//
+func keyBits[K comparable](key K) uint64 {
+	p := unsafe.Pointer(&key)
+	if unsafe.Sizeof(key) == 4 {
+		return uint64(*(*uint32)(p))
+	}
+	return *(*uint64)(p)
+}
+
+func (m *table[K, V]) probe(key K) (idx int, found bool) {
+	var free K
+	mod := len(m.es) - 1
+	if mod < 0 {
+		return -1, false
+	}
+	idx = m.hash(key) & mod
+	for {
+		switch m.es[idx].Key {
+		case free:
+			return idx, false
+		case key:
+			return idx, true
+		}
+		idx = nextIdx(idx) & mod
+	}
+}
+
+func (m *table[K, V]) resize(l int) {
+	var free K
+	if len(m.es) == 0 {
+		m.seed = rand.Uint64()
+	}
+	es := m.es
+	m.es = make([]KeyValueOf[K, V], l)
+	m.threshold = thresholdFor(l, m.fillratio)
+	mod := l - 1
+	for i := range es {
+		k := es[i].Key
+		if k == free {
+			continue
+		}
+		idx := m.hash(k) & mod
+		for m.es[idx].Key != free {
+			idx = nextIdx(idx) & mod
+		}
+		m.es[idx] = es[i]
+	}
+}
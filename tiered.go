@@ -0,0 +1,121 @@
+// Copyright 2019 Denis Bernard <db047h@gmail.com>
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package intmap
+
+const defaultHotLen = 512
+
+// TieredMap is a two-level integer keyed map for workloads with strong temporal
+// locality. New keys are inserted in a small hot table that stays in cache,
+// while the bulk of the keys is held in a large cold table. When the hot table
+// is full, its keys are merged into the cold table in one go.
+//
+// Rehashes of the hot table are bounded by its size, and the cold table is only
+// grown during merges, once per batch of new keys. Updating a key that is
+// already in the cold table is done in place.
+//
+// The zero value is an empty map ready to use, with a hot table of 512 keys. A
+// TieredMap must not be copied after first use.
+//
+type TieredMap[V any] struct {
+	hot    Map[V]
+	cold   Map[V]
+	hotMax int
+}
+
+// SetHotLen sets the maximum number of keys held in the hot table. A value of
+// n <= 0 restores the default of 512. Any keys in the hot table are first
+// merged into the cold table.
+//
+func (m *TieredMap[V]) SetHotLen(n int) {
+	if n <= 0 {
+		n = defaultHotLen
+	}
+	m.hotMax = n
+	m.Merge()
+	m.hot.Init(0, defaultFillRatio)
+	m.hot.Grow(n)
+}
+
+func (m *TieredMap[V]) hotLen() int {
+	if m.hotMax == 0 {
+		m.SetHotLen(defaultHotLen)
+	}
+	return m.hotMax
+}
+
+// Set sets the value for the given key.
+//
+func (m *TieredMap[V]) Set(key int, value V) {
+	if p, ok := m.cold.valuePtr(key); ok {
+		*p = value
+		return
+	}
+	if _, existed := m.hot.Swap(key, value); !existed && m.hot.Len() >= m.hotLen() {
+		m.Merge()
+	}
+}
+
+// Merge moves all keys from the hot table to the cold table.
+//
+func (m *TieredMap[V]) Merge() {
+	if m.hot.Len() == 0 {
+		return
+	}
+	m.cold.Grow(m.hot.Len())
+	m.hot.Range(func(key int, value V) bool {
+		m.cold.Set(key, value)
+		return true
+	})
+	m.hot.Clear()
+}
+
+// Get returns the value associated with the given key and true, or the zero
+// value for V and false if the key is not set.
+//
+func (m *TieredMap[V]) Get(key int) (v V, ok bool) {
+	if v, ok = m.hot.Get(key); ok {
+		return v, ok
+	}
+	return m.cold.Get(key)
+}
+
+// Has returns true if the given key is set.
+//
+func (m *TieredMap[V]) Has(key int) bool {
+	return m.hot.Has(key) || m.cold.Has(key)
+}
+
+// Delete deletes the given key and returns true if it was set.
+//
+func (m *TieredMap[V]) Delete(key int) bool {
+	return m.hot.Delete(key) || m.cold.Delete(key)
+}
+
+// Clear removes all keys from the map. It keeps the allocated storage.
+//
+func (m *TieredMap[V]) Clear() {
+	m.hot.Clear()
+	m.cold.Clear()
+}
+
+// Len returns the number if keys set in the map.
+//
+func (m *TieredMap[V]) Len() int {
+	return m.hot.Len() + m.cold.Len()
+}
+
+// Range calls f sequentially for each key and value present in the map. If f
+// returns false, Range stops the iteration. f must not modify the map.
+//
+func (m *TieredMap[V]) Range(f func(key int, value V) bool) {
+	stop := false
+	m.hot.Range(func(key int, value V) bool {
+		stop = !f(key, value)
+		return !stop
+	})
+	if !stop {
+		m.cold.Range(f)
+	}
+}
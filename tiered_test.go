@@ -0,0 +1,46 @@
+package intmap_test
+
+import (
+	"testing"
+
+	"github.com/db47h/intmap"
+)
+
+func TestTieredMap(t *testing.T) {
+	var m intmap.TieredMap[int]
+	m.SetHotLen(64)
+	const n = 1000
+	for i := 0; i < n; i++ {
+		m.Set(i*7, i)
+	}
+	// updates of merged keys
+	for i := 0; i < n; i += 3 {
+		m.Set(i*7, -i)
+	}
+	for i := 0; i < n; i += 2 {
+		if !m.Delete(i * 7) {
+			t.Errorf("Delete(%d) returned false", i*7)
+		}
+	}
+	if m.Len() != n/2 {
+		t.Fatalf("Len() = %d, expected %d", m.Len(), n/2)
+	}
+	for i := 0; i < n; i++ {
+		exp := i
+		if i%3 == 0 {
+			exp = -i
+		}
+		v, ok := m.Get(i * 7)
+		if ok != (i&1 != 0) || (ok && v != exp) {
+			t.Errorf("bad value for key %d: %d, %v", i*7, v, ok)
+		}
+	}
+	cnt := 0
+	m.Range(func(k, v int) bool {
+		cnt++
+		return true
+	})
+	if cnt != n/2 {
+		t.Errorf("Range visited %d keys, expected %d", cnt, n/2)
+	}
+}